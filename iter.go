@@ -0,0 +1,23 @@
+//go:build go1.23
+
+package tokenizer
+
+import "iter"
+
+// All returns an iterator over the tokens, calling `NextToken`.
+// The iteration stops after the first error, and
+// before the EOF token.
+func (tk *Tokenizer) All() iter.Seq2[Token, error] {
+	return func(yield func(Token, error) bool) {
+		for {
+			t, err := tk.NextToken()
+			if err != nil {
+				yield(t, err)
+				return
+			}
+			if t.Kind == EOF || !yield(t, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+//go:build go1.23
+
+package tokenizer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	input := []byte("<< /Type /Page /Kids [1 0 R] (s) >> {1 2 add}")
+	exp, err := Tokenize(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Token
+	for tk, err := range NewTokenizer(input).All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, tk)
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+
+	var nbErrors, nbTokens int
+	for _, err := range NewTokenizer([]byte("1 2 (abc")).All() {
+		if err != nil {
+			nbErrors++
+		} else {
+			nbTokens++
+		}
+	}
+	if nbTokens != 2 || nbErrors != 1 {
+		t.Errorf("expected 2 tokens and 1 error, got %d and %d", nbTokens, nbErrors)
+	}
+
+	// early break
+	for range NewTokenizer(input).All() {
+		break
+	}
+}
@@ -0,0 +1,39 @@
+package tokenizer
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// MarshalText implements encoding.TextMarshaler, using `String`.
+func (k Kind) MarshalText() ([]byte, error) { return []byte(k.String()), nil }
+
+type tokenJSON struct {
+	Kind  Kind    `json:"kind"`
+	Value *string `json:"value,omitempty"`
+	Hex   *string `json:"hex,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, emitting objects like {"kind":"Name","value":"Type"}.
+// Binary values (hex strings, charstrings and strings which are not valid UTF-8)
+// are hex encoded, in a "hex" field.
+// Tokens without value (like StartDic) only have a "kind" field.
+func (t Token) MarshalJSON() ([]byte, error) {
+	out := tokenJSON{Kind: t.Kind}
+	switch t.Kind {
+	case StartArray, EndArray, StartDic, EndDic, StartProc, EndProc, EOF:
+	case StringHex, CharString:
+		h := hex.EncodeToString(t.Value)
+		out.Hex = &h
+	default:
+		if t.Kind == String && !utf8.Valid(t.Value) {
+			h := hex.EncodeToString(t.Value)
+			out.Hex = &h
+		} else {
+			v := string(t.Value)
+			out.Value = &v
+		}
+	}
+	return json.Marshal(out)
+}
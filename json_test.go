@@ -0,0 +1,35 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	tks, err := Tokenize([]byte("<< /Type /Page /Count 3 /T (a\nb) /U (\xff\xfe) /V <FEFF> /W / >> 1.5 true"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(tks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `[{"kind":"StartDic"},{"kind":"Name","value":"Type"},{"kind":"Name","value":"Page"},` +
+		`{"kind":"Name","value":"Count"},{"kind":"Integer","value":"3"},` +
+		`{"kind":"Name","value":"T"},{"kind":"String","value":"a\nb"},` +
+		`{"kind":"Name","value":"U"},{"kind":"String","hex":"fffe"},` +
+		`{"kind":"Name","value":"V"},{"kind":"StringHex","hex":"feff"},` +
+		`{"kind":"Name","value":"W"},{"kind":"Name","value":""},{"kind":"EndDic"},` +
+		`{"kind":"Float","value":"1.5"},{"kind":"Other","value":"true"}]`
+	if string(got) != exp {
+		t.Errorf("expected\n%s\ngot\n%s", exp, got)
+	}
+
+	got, err = json.Marshal(Token{Kind: CharString, Value: []byte{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := `{"kind":"CharString","hex":"0102"}`; string(got) != exp {
+		t.Errorf("expected %s, got %s", exp, got)
+	}
+}
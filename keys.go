@@ -0,0 +1,84 @@
+package tokenizer
+
+// IsStandardKey returns true if the token is a Name
+// among the common dictionary keys defined in the PDF specification.
+// It may be used to flag unusual (or misspelled) keys.
+func (t Token) IsStandardKey() bool {
+	if t.Kind != Name {
+		return false
+	}
+	_, ok := standardKeys[string(t.Value)]
+	return ok
+}
+
+// standardKeys contains the keys of the common dictionaries
+// (document structure, pages, resources, fonts, images, streams,
+// annotations, actions, forms, encryption, color spaces...)
+var standardKeys = map[string]struct{}{
+	// trailer, xref and object streams
+	"Size": {}, "Prev": {}, "Root": {}, "Encrypt": {}, "Info": {}, "ID": {}, "XRefStm": {}, "Index": {}, "W": {}, "N": {}, "First": {}, "Extends": {},
+	// streams
+	"Length": {}, "Filter": {}, "DecodeParms": {}, "F": {}, "FFilter": {}, "FDecodeParms": {}, "DL": {},
+	"Predictor": {}, "Colors": {}, "BitsPerComponent": {}, "Columns": {}, "EarlyChange": {}, "K": {}, "EndOfLine": {},
+	"EncodedByteAlign": {}, "Rows": {}, "EndOfBlock": {}, "BlackIs1": {}, "DamagedRowsBeforeError": {}, "JBIG2Globals": {}, "ColorTransform": {},
+	// generic
+	"Type": {}, "Subtype": {}, "Name": {}, "Version": {}, "Metadata": {}, "PieceInfo": {}, "LastModified": {}, "StructParents": {}, "StructParent": {},
+	// catalog
+	"Pages": {}, "PageLabels": {}, "Names": {}, "Dests": {}, "ViewerPreferences": {}, "PageLayout": {}, "PageMode": {}, "Outlines": {},
+	"Threads": {}, "OpenAction": {}, "AA": {}, "URI": {}, "AcroForm": {}, "MarkInfo": {}, "Lang": {}, "SpiderInfo": {}, "OutputIntents": {},
+	"OCProperties": {}, "Perms": {}, "Legal": {}, "Requirements": {}, "Collection": {}, "NeedsRendering": {}, "StructTreeRoot": {},
+	// info
+	"Title": {}, "Author": {}, "Subject": {}, "Keywords": {}, "Creator": {}, "Producer": {}, "CreationDate": {}, "ModDate": {}, "Trapped": {},
+	// page tree and pages
+	"Parent": {}, "Kids": {}, "Count": {}, "Resources": {}, "MediaBox": {}, "CropBox": {}, "BleedBox": {}, "TrimBox": {}, "ArtBox": {},
+	"BoxColorInfo": {}, "Contents": {}, "Rotate": {}, "Group": {}, "Thumb": {}, "B": {}, "Dur": {}, "Trans": {}, "Annots": {}, "Tabs": {},
+	"TemplateInstantiated": {}, "PresSteps": {}, "UserUnit": {}, "VP": {},
+	// resources
+	"ExtGState": {}, "ColorSpace": {}, "Pattern": {}, "Shading": {}, "XObject": {}, "Font": {}, "ProcSet": {}, "Properties": {},
+	// outlines
+	"Last": {}, "Next": {}, "Dest": {}, "A": {}, "SE": {}, "C": {},
+	// fonts
+	"BaseFont": {}, "FirstChar": {}, "LastChar": {}, "Widths": {}, "FontDescriptor": {}, "Encoding": {}, "ToUnicode": {},
+	"DescendantFonts": {}, "CIDSystemInfo": {}, "DW": {}, "W2": {}, "DW2": {}, "CIDToGIDMap": {}, "Registry": {}, "Ordering": {}, "Supplement": {},
+	"FontBBox": {}, "FontMatrix": {}, "CharProcs": {}, "Differences": {}, "BaseEncoding": {},
+	"FontName": {}, "FontFamily": {}, "FontStretch": {}, "FontWeight": {}, "Flags": {}, "ItalicAngle": {}, "Ascent": {}, "Descent": {},
+	"Leading": {}, "CapHeight": {}, "XHeight": {}, "StemV": {}, "StemH": {}, "AvgWidth": {}, "MaxWidth": {}, "MissingWidth": {},
+	"FontFile": {}, "FontFile2": {}, "FontFile3": {}, "CharSet": {}, "Style": {}, "FD": {}, "CIDSet": {},
+	// images and XObjects
+	"Width": {}, "Height": {}, "ImageMask": {}, "Mask": {}, "SMask": {}, "Decode": {}, "Interpolate": {}, "Alternates": {}, "SMaskInData": {},
+	"Intent": {}, "OPI": {}, "OC": {}, "BBox": {}, "Matrix": {}, "FormType": {}, "Ref": {},
+	// graphics states
+	"LW": {}, "LC": {}, "LJ": {}, "ML": {}, "D": {}, "RI": {}, "OP": {}, "op": {}, "OPM": {}, "BG": {}, "BG2": {}, "UCR": {}, "UCR2": {}, "TR": {}, "TR2": {}, "HT": {},
+	"FL": {}, "SM": {}, "SA": {}, "BM": {}, "CA": {}, "ca": {}, "AIS": {}, "TK": {},
+	// shadings, patterns and functions
+	"ShadingType": {}, "Background": {}, "AntiAlias": {}, "Coords": {}, "Domain": {}, "Extend": {}, "Function": {}, "Functions": {},
+	"PatternType": {}, "PaintType": {}, "TilingType": {}, "XStep": {}, "YStep": {}, "FunctionType": {}, "Range": {}, "Encode": {},
+	"BitsPerSample": {}, "Order": {}, "C0": {}, "C1": {}, "Bounds": {}, "BitsPerCoordinate": {}, "BitsPerFlag": {}, "VerticesPerRow": {},
+	// color spaces
+	"WhitePoint": {}, "BlackPoint": {}, "Gamma": {}, "Alternate": {},
+	// annotations
+	"Rect": {}, "NM": {}, "M": {}, "AP": {}, "AS": {}, "Border": {}, "BS": {}, "BE": {}, "Popup": {}, "Open": {}, "IC": {},
+	"RC": {}, "IRT": {}, "RT": {}, "Q": {}, "QuadPoints": {}, "LE": {}, "L": {}, "H": {}, "Vertices": {}, "InkList": {}, "DA": {}, "DS": {}, "CL": {}, "IT": {},
+	"Line": {}, "LL": {}, "LLE": {}, "Cap": {}, "Sound": {}, "FS": {}, "Movie": {}, "MK": {}, "R": {}, "S": {},
+	// actions and destinations
+	"JS": {}, "NewWindow": {}, "Win": {}, "Mac": {}, "Unix": {}, "SD": {}, "IsMap": {}, "T": {}, "Hide": {},
+	// forms
+	"Fields": {}, "NeedAppearances": {}, "SigFlags": {}, "CO": {}, "DR": {}, "XFA": {}, "FT": {}, "TU": {}, "TM": {}, "Ff": {}, "V": {}, "DV": {},
+	"Opt": {}, "TI": {}, "I": {}, "MaxLen": {},
+	// file specifications
+	"UF": {}, "EF": {}, "RF": {}, "Desc": {}, "CI": {}, "Params": {}, "CheckSum": {},
+	// encryption and signatures
+	"CF": {}, "StmF": {}, "StrF": {}, "EFF": {}, "O": {}, "U": {}, "OE": {}, "UE": {}, "P": {}, "EncryptMetadata": {},
+	"CFM": {}, "AuthEvent": {}, "Recipients": {}, "ByteRange": {}, "Cert": {}, "Reference": {}, "Changes": {}, "Location": {},
+	"Reason": {}, "ContactInfo": {}, "Prop_Build": {}, "Prop_AuthTime": {}, "Prop_AuthType": {},
+	// structure and marked content
+	"Pg": {}, "Alt": {}, "ActualText": {}, "E": {}, "RoleMap": {}, "ClassMap": {}, "ParentTree": {}, "ParentTreeNextKey": {},
+	"IDTree": {}, "Marked": {}, "UserProperties": {}, "Suspects": {},
+	// optional content
+	"OCGs": {}, "Usage": {}, "Configs": {}, "ON": {}, "OFF": {}, "BaseState": {}, "Event": {}, "Category": {}, "VE": {}, "RBGroups": {},
+	"Locked": {}, "Print": {}, "View": {}, "Export": {}, "Zoom": {}, "Language": {}, "PageElement": {},
+	// name and number trees
+	"Nums": {}, "Limits": {},
+	// output intents
+	"OutputCondition": {}, "OutputConditionIdentifier": {}, "RegistryName": {}, "DestOutputProfile": {},
+}
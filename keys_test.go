@@ -0,0 +1,21 @@
+package tokenizer
+
+import "testing"
+
+func TestIsStandardKey(t *testing.T) {
+	for _, test := range []struct {
+		tk  Token
+		exp bool
+	}{
+		{Token{Kind: Name, Value: []byte("Type")}, true},
+		{Token{Kind: Name, Value: []byte("MediaBox")}, true},
+		{Token{Kind: Name, Value: []byte("Length")}, true},
+		{Token{Kind: Name, Value: []byte("MyCustomKey")}, false},
+		{Token{Kind: Name, Value: []byte("type")}, false},
+		{Token{Kind: String, Value: []byte("Type")}, false},
+	} {
+		if got := test.tk.IsStandardKey(); got != test.exp {
+			t.Errorf("%v: expected %v, got %v", test.tk, test.exp, got)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package tokenizer
+
+import "sort"
+
+// lineIndex stores the start offsets of the lines
+// of the input, built incrementally.
+type lineIndex struct {
+	starts  []int // offsets of the line starts, starting with 0
+	indexed int   // number of bytes already scanned
+}
+
+func (li *lineIndex) reset() {
+	li.starts = li.starts[:0]
+	li.indexed = 0
+}
+
+// update scans data[li.indexed:], recording the line starts.
+// `\n`, `\r\n` and a lone `\r` are all considered as EOL.
+func (li *lineIndex) update(data []byte) {
+	if len(li.starts) == 0 {
+		li.starts = append(li.starts, 0)
+	}
+	for i := li.indexed; i < len(data); i++ {
+		switch data[i] {
+		case '\n':
+			if i > 0 && data[i-1] == '\r' {
+				// the line start has already been recorded after '\r'
+				li.starts[len(li.starts)-1] = i + 1
+			} else {
+				li.starts = append(li.starts, i+1)
+			}
+		case '\r':
+			li.starts = append(li.starts, i+1)
+		}
+	}
+	li.indexed = len(data)
+}
+
+// Position returns the line and column (both starting at 1)
+// of the given byte offset in the input.
+// Offsets past the end of the input (or the current internal buffer in
+// reader mode) are clamped.
+// Since the lines are computed from the start of the input, the result
+// does not depend on the current position, and is not affected
+// by `SetPosition`.
+// Use `CurrentPosition` as `offset` to locate the end of the last token read.
+// In random access mode (see `NewTokenizerFromReaderAt`) or after `CompactBuffer`,
+// only the lines of the internal buffer are counted.
+func (pr *Tokenizer) Position(offset int) (line, col int) {
+	if pr.lines.indexed < len(pr.data) || len(pr.lines.starts) == 0 {
+		pr.lines.update(pr.data)
+	}
+	offset -= pr.base
+	if offset > len(pr.data) {
+		offset = len(pr.data)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	// index of the first line starting after offset
+	i := sort.SearchInts(pr.lines.starts, offset+1)
+	return i, offset - pr.lines.starts[i-1] + 1
+}
+
+// LineEnding is the style of end-of-line markers.
+type LineEnding uint8
+
+const (
+	NoLineEnding    LineEnding = iota // no EOL found
+	LF                                // \n
+	CRLF                              // \r\n
+	CR                                // \r
+	MixedLineEnding                   // several styles, equally frequent
+)
+
+func (le LineEnding) String() string {
+	switch le {
+	case NoLineEnding:
+		return "NoLineEnding"
+	case LF:
+		return "LF"
+	case CRLF:
+		return "CRLF"
+	case CR:
+		return "CR"
+	case MixedLineEnding:
+		return "MixedLineEnding"
+	default:
+		return "<invalid line ending>"
+	}
+}
+
+// DetectLineEnding scans `data` and returns the most frequent style of its EOL markers,
+// or MixedLineEnding if several styles are used equally often.
+// Note that binary content (like streams) may contain
+// arbitrary EOL bytes, so that callers should only pass textual content.
+func DetectLineEnding(data []byte) LineEnding {
+	var counts [MixedLineEnding]int
+	for i := 0; i < len(data); i++ {
+		if !isEOL(data[i]) {
+			continue
+		}
+		if data[i] == '\n' {
+			counts[LF]++
+		} else if i+1 < len(data) && data[i+1] == '\n' {
+			counts[CRLF]++
+			i++
+		} else {
+			counts[CR]++
+		}
+	}
+	out, best := NoLineEnding, 0
+	for le, c := range counts {
+		if c == 0 {
+			continue
+		}
+		if c == best {
+			out = MixedLineEnding
+		} else if c > best {
+			out, best = LineEnding(le), c
+		}
+	}
+	return out
+}
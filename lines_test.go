@@ -0,0 +1,86 @@
+package tokenizer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPosition(t *testing.T) {
+	input := []byte("1 0 obj\r\n<< /A 1\r/B (x\ny) >>\n\rendobj")
+	for _, test := range []struct {
+		offset    int
+		line, col int
+	}{
+		{0, 1, 1},
+		{6, 1, 7},
+		{7, 1, 8},  // \r
+		{8, 1, 9},  // \n of \r\n
+		{9, 2, 1},  // <<
+		{16, 2, 8}, // lone \r
+		{17, 3, 1}, // /B
+		{23, 4, 1}, // y
+		{30, 6, 1}, // endobj, after \n\r
+		{100, 6, 7},
+		{-1, 1, 1},
+	} {
+		tk := NewTokenizer(input)
+		if line, col := tk.Position(test.offset); line != test.line || col != test.col {
+			t.Errorf("offset %d: expected %d:%d, got %d:%d", test.offset, test.line, test.col, line, col)
+		}
+	}
+
+	// SetPosition jumps do not change the results
+	tk := NewTokenizer(input)
+	tk.SetPosition(17)
+	if _, err := tk.NextToken(); err != nil {
+		t.Fatal(err)
+	}
+	if line, col := tk.Position(tk.CurrentPosition()); line != 3 || col != 3 {
+		t.Errorf("expected 3:3, got %d:%d", line, col)
+	}
+	tk.SetPosition(0)
+	if line, col := tk.Position(30); line != 6 || col != 1 {
+		t.Errorf("expected 6:1, got %d:%d", line, col)
+	}
+}
+
+func TestResetLines(t *testing.T) {
+	tk := NewTokenizer([]byte("1\n2\n3\n4"))
+	tk.readAll()
+	if line, col := tk.Position(6); line != 4 || col != 1 {
+		t.Fatalf("expected 4:1, got %d:%d", line, col)
+	}
+
+	tk.Reset([]byte("1 2 3 4"))
+	tk.readAll()
+	if line, col := tk.Position(6); line != 1 || col != 7 {
+		t.Fatalf("expected 1:7, got %d:%d", line, col)
+	}
+
+	tk.ResetFromReader(strings.NewReader("1 2\n\n3 4"))
+	tk.readAll()
+	if line, col := tk.Position(6); line != 3 || col != 2 {
+		t.Fatalf("expected 3:2, got %d:%d", line, col)
+	}
+}
+
+func TestDetectLineEnding(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		exp   LineEnding
+	}{
+		{"1 0 obj << >> endobj", NoLineEnding},
+		{"1 0 obj\n<< >>\nendobj\n", LF},
+		{"1 0 obj\r\n<< >>\r\nendobj\r\n", CRLF},
+		{"1 0 obj\r<< >>\rendobj\r", CR},
+		{"1 0 obj\r\n<< >>\nendobj", MixedLineEnding},
+		{"1 0 obj\r<< >>\r\nendobj", MixedLineEnding},
+		{"1 0 obj\r\n<< >>\r\nendobj\n2 0 obj\r\n<< >>\r\nendobj\r\n", CRLF},
+		{"1 0 obj\n<< >>\rendobj\n", LF},
+		{"1\n2\n3\r\n4\r\n5\r", MixedLineEnding},
+	} {
+		if got := DetectLineEnding([]byte(test.input)); got != test.exp {
+			t.Errorf("expected %s, got %s", test.exp, got)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+//go:build linux
+
+package tokenizer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func TestMmap(t *testing.T) {
+	input := []byte("<< /Type /Page /Contents 4 0 R /Annots [(a note) <FEFF>] >> 1.5 true")
+	path := filepath.Join(t.TempDir(), "file.pdf")
+	if err := os.WriteFile(path, input, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	// read-only mapping: any write from the tokenizer would crash
+	data, err := syscall.Mmap(int(f.Fd()), 0, len(input), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		t.Skip("mmap not supported:", err)
+	}
+	tks, err := Tokenize(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// in zero-copy mode, tokens alias the mapping
+	zc, err := NewTokenizer(data, WithZeroCopy()).readAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !aliases(zc[1].Value, data) || !zc[1].Equal(Token{Kind: Name, Value: []byte("Type")}) {
+		t.Errorf("expected aliased Type name, got %v", zc[1])
+	}
+
+	if err = syscall.Munmap(data); err != nil {
+		t.Fatal(err)
+	}
+
+	// tokens do not alias the (now invalid) mapping
+	exp, err := Tokenize(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tks, exp) {
+		t.Errorf("expected %v, got %v", exp, tks)
+	}
+}
@@ -0,0 +1,459 @@
+package tokenizer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// DictEntry stores one (top-level) entry of a dictionary,
+// with the raw tokens of its value.
+type DictEntry struct {
+	Key string
+	// ValueTokens are the tokens of the value, which
+	// may be composite (array, dictionary or indirect reference).
+	ValueTokens []Token
+}
+
+// ReadDictEntries reads a dictionary, starting at the next token, and
+// returns its top-level entries, without building the values.
+func (pr *Tokenizer) ReadDictEntries() ([]DictEntry, error) {
+	tk, err := pr.NextToken()
+	if err != nil {
+		return nil, err
+	}
+	if tk.Kind != StartDic {
+		return nil, fmt.Errorf("expected StartDic, got %s", tk.Kind)
+	}
+	var out []DictEntry
+	for {
+		tk, err = pr.NextToken()
+		if err != nil {
+			return nil, err
+		}
+		switch tk.Kind {
+		case EndDic:
+			return out, nil
+		case Name:
+		case EOF:
+			return nil, errors.New("unexpected EOF in dictionary")
+		default:
+			return nil, fmt.Errorf("expected Name as dictionary key, got %s", tk.Kind)
+		}
+		value, err := pr.readValue()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, DictEntry{Key: string(tk.Value), ValueTokens: value})
+	}
+}
+
+// readValue reads the tokens of the next object, consuming
+// balanced arrays, dictionaries and procs, and
+// indirect references.
+func (pr *Tokenizer) readValue() ([]Token, error) {
+	tk, err := pr.NextToken()
+	if err != nil {
+		return nil, err
+	}
+	switch tk.Kind {
+	case EOF:
+		return nil, errors.New("unexpected EOF: missing value")
+	case EndArray, EndDic, EndProc:
+		return nil, fmt.Errorf("unexpected %s: missing value", tk.Kind)
+	case Integer:
+		// check for an indirect reference
+		gen, _ := pr.PeekToken()
+		r, _ := pr.PeekPeekToken()
+		if gen.Kind == Integer && r.IsOther("R") {
+			pr.NextToken()
+			pr.NextToken()
+			return []Token{tk, gen, r}, nil
+		}
+		return []Token{tk}, nil
+	case StartArray, StartDic, StartProc:
+		out := []Token{tk}
+		depth, procDepth := 1, 0
+		if tk.Kind == StartProc {
+			procDepth = 1
+		}
+		for depth > 0 {
+			tk, err = pr.NextToken()
+			if err != nil {
+				return nil, err
+			}
+			switch tk.Kind {
+			case EOF:
+				return nil, errors.New("unexpected EOF in composite value")
+			case StartProc:
+				procDepth++
+				if limit := pr.maxProcNesting(); procDepth > limit {
+					return nil, fmt.Errorf("procedure nesting exceeds limit (%d)", limit)
+				}
+				depth++
+			case EndProc:
+				procDepth--
+				depth--
+			case StartArray, StartDic:
+				depth++
+			case EndArray, EndDic:
+				depth--
+			}
+			out = append(out, tk)
+		}
+		return out, nil
+	default:
+		return []Token{tk}, nil
+	}
+}
+
+// SkipObject skips the indirect object `num` `gen`, which must start at the next token,
+// positioning the tokenizer after its 'endobj' keyword.
+// Streams are skipped by looking for the 'endstream' keyword.
+// If the next tokens are not the expected object header, an error is returned
+// and the position is left unchanged.
+func (pr *Tokenizer) SkipObject(num, gen int) error {
+	start := pr.CurrentPosition()
+	if err := pr.expectObjectHeader(num, gen); err != nil {
+		pr.SetPosition(start)
+		return err
+	}
+	if _, err := pr.readObjectBody(); err != nil {
+		return fmt.Errorf("object %d %d: %s", num, gen, err)
+	}
+	return nil
+}
+
+// expectObjectHeader reads 'num gen obj'
+func (pr *Tokenizer) expectObjectHeader(num, gen int) error {
+	var header [3]Token
+	for i := range header {
+		tk, err := pr.NextToken()
+		if err != nil {
+			return err
+		}
+		header[i] = tk
+	}
+	if header[0].Kind != Integer || header[1].Kind != Integer || !header[2].IsOther("obj") {
+		return fmt.Errorf("expected object header, got %v", header)
+	}
+	n, err := header[0].Int()
+	if err != nil {
+		return err
+	}
+	g, err := header[1].Int()
+	if err != nil {
+		return err
+	}
+	if n != num || g != gen {
+		return fmt.Errorf("expected object %d %d, got %d %d", num, gen, n, g)
+	}
+	return nil
+}
+
+// indexFrom returns the position of the first occurence of `sep`
+// after `pos`, or -1. In reader mode, the buffer is grown as needed.
+func (pr *Tokenizer) indexFrom(pos int, sep []byte) int {
+	for from := pos; ; {
+		if from <= len(pr.data) {
+			if i := bytes.Index(pr.data[from:], sep); i != -1 {
+				return from + i
+			}
+		}
+		L := len(pr.data)
+		if !pr.growTo(L + 1) { // no more data
+			return -1
+		}
+		// sep may straddle the previous buffer end
+		if from < L-len(sep)+1 {
+			from = L - len(sep) + 1
+		}
+	}
+}
+
+// ObjectTokens stores the tokens of an indirect object.
+type ObjectTokens struct {
+	Num, Gen int
+	// Tokens are the tokens between 'obj' and 'endobj' (both excluded).
+	// For objects with a stream, the last token is the 'stream' keyword.
+	Tokens []Token
+}
+
+// SplitObjects tokenizes `data`, returning the indirect objects found.
+// Stream contents are skipped, by looking for the 'endstream' keyword,
+// and the tokens outside of objects (like the trailer) are ignored.
+func SplitObjects(data []byte) ([]ObjectTokens, error) {
+	tk := NewTokenizer(data)
+	var out []ObjectTokens
+	for {
+		t, err := tk.NextToken()
+		if err != nil {
+			return nil, err
+		}
+		if t.Kind == EOF {
+			return out, nil
+		}
+		gen, _ := tk.PeekToken()
+		obj, _ := tk.PeekPeekToken()
+		if !(t.Kind == Integer && gen.Kind == Integer && obj.IsOther("obj")) {
+			continue
+		}
+		tk.NextToken()
+		tk.NextToken()
+		object := ObjectTokens{}
+		if object.Num, err = t.Int(); err != nil {
+			return nil, err
+		}
+		if object.Gen, err = gen.Int(); err != nil {
+			return nil, err
+		}
+		if object.Tokens, err = tk.readObjectBody(); err != nil {
+			return nil, fmt.Errorf("object %d %d: %s", object.Num, object.Gen, err)
+		}
+		out = append(out, object)
+	}
+}
+
+// readObjectBody reads until 'endobj', skipping streams.
+func (pr *Tokenizer) readObjectBody() ([]Token, error) {
+	var out []Token
+	for {
+		tk, err := pr.NextToken()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case tk.Kind == EOF:
+			return nil, errors.New("unexpected EOF: missing endobj")
+		case tk.IsOther("endobj"):
+			return out, nil
+		case tk.IsOther("stream"):
+			out = append(out, tk)
+			end := pr.indexFrom(pr.streamPos(), []byte("endstream"))
+			if end == -1 {
+				return nil, errors.New("missing endstream")
+			}
+			pr.setPosition(end + len("endstream"))
+			if err = pr.expectEndobj(); err != nil {
+				return nil, err
+			}
+			return out, nil
+		default:
+			out = append(out, tk)
+		}
+	}
+}
+
+func (pr *Tokenizer) expectEndobj() error {
+	tk, err := pr.NextToken()
+	if err != nil {
+		return err
+	}
+	if !tk.IsOther("endobj") {
+		return fmt.Errorf("expected endobj, got %v", tk)
+	}
+	return nil
+}
+
+// ReadProcRaw reads a PostScript procedure, which must start at the next token,
+// and returns its source bytes, from '{' to the matching '}' (included).
+// An error is returned if the nesting of procedures exceeds `MaxProcNesting`.
+func (pr *Tokenizer) ReadProcRaw() ([]byte, error) {
+	tk, err := pr.NextToken()
+	if err != nil {
+		return nil, err
+	}
+	if tk.Kind != StartProc {
+		return nil, fmt.Errorf("expected StartProc, got %s", tk.Kind)
+	}
+	start := pr.currentPos - 1
+	for depth := 1; depth > 0; {
+		tk, err = pr.NextToken()
+		if err != nil {
+			return nil, err
+		}
+		switch tk.Kind {
+		case EOF:
+			return nil, errors.New("unexpected EOF in procedure")
+		case StartProc:
+			depth++
+			if limit := pr.maxProcNesting(); depth > limit {
+				return nil, fmt.Errorf("procedure nesting exceeds limit (%d)", limit)
+			}
+		case EndProc:
+			depth--
+		}
+	}
+	return copyBytes(pr.data[start:pr.currentPos]), nil
+}
+
+const defaultMaxProcNesting = 1000
+
+func (pr *Tokenizer) maxProcNesting() int {
+	if pr.MaxProcNesting > 0 {
+		return pr.MaxProcNesting
+	}
+	return defaultMaxProcNesting
+}
+
+// ReadInlineImageData reads the data of an inline image, which must
+// follow the last token read (the 'ID' keyword), and returns it.
+// The data ends with the first 'EI' keyword surrounded by white spaces,
+// so that 'EI' bytes inside the binary data are skipped if possible.
+// The tokenizer is positionned after the 'EI' keyword.
+func (pr *Tokenizer) ReadInlineImageData() ([]byte, error) {
+	if tk, _ := pr.LastToken(); !tk.IsOther("ID") {
+		return nil, fmt.Errorf("expected ID before inline image data, got %v", tk)
+	}
+	start := pr.currentPos + 1 // single white space after ID
+	end := pr.inlineImageEnd(start)
+	if end == -1 {
+		return nil, errors.New("missing EI after inline image data")
+	}
+	out := copyBytes(pr.data[start : end-1])
+	pr.setPosition(end + 2)
+	return out, nil
+}
+
+// inlineImageEnd returns the position of the first 'EI' after `start`
+// surrounded by white spaces, or -1
+func (pr *Tokenizer) inlineImageEnd(start int) int {
+	for from := start; ; {
+		end := pr.indexFrom(from, []byte("EI"))
+		if end == -1 {
+			return -1
+		}
+		after := end + 2
+		if after >= len(pr.data) && pr.src != nil {
+			pr.grow(1)
+		}
+		if end > start && IsAsciiWhitespace(pr.data[end-1]) &&
+			(after >= len(pr.data) || IsAsciiWhitespace(pr.data[after])) {
+			return end
+		}
+		from = end + 1
+	}
+}
+
+// Reference is an indirect reference (like 12 0 R).
+type Reference struct {
+	Num, Gen int
+}
+
+// ReadReferenceArray reads an array of indirect references (like 1 0 R 2 0 R),
+// which must start at the next token.
+func (pr *Tokenizer) ReadReferenceArray() ([]Reference, error) {
+	tk, err := pr.NextToken()
+	if err != nil {
+		return nil, err
+	}
+	if tk.Kind != StartArray {
+		return nil, fmt.Errorf("expected StartArray, got %s", tk.Kind)
+	}
+	var out []Reference
+	for {
+		tk, err = pr.NextToken()
+		if err != nil {
+			return nil, err
+		}
+		switch tk.Kind {
+		case EndArray:
+			return out, nil
+		case EOF:
+			return nil, errors.New("unexpected EOF in array")
+		}
+		gen, err := pr.NextToken()
+		if err != nil {
+			return nil, err
+		}
+		r, err := pr.NextToken()
+		if err != nil {
+			return nil, err
+		}
+		if tk.Kind != Integer || gen.Kind != Integer || !r.IsOther("R") {
+			return nil, fmt.Errorf("invalid reference %s %s %s", tk.Value, gen.Value, r.Value)
+		}
+		var ref Reference
+		if ref.Num, err = tk.Int(); err != nil {
+			return nil, err
+		}
+		if ref.Gen, err = gen.Int(); err != nil {
+			return nil, err
+		}
+		out = append(out, ref)
+	}
+}
+
+// ReadStreamData reads the content of a stream, which must follow
+// the last token read (the 'stream' keyword), with the given length.
+// The end-of-line marker after 'stream' is skipped, and
+// the tokenizer is positionned after the data, that is before 'endstream'.
+// An error is returned if the input is too short.
+func (pr *Tokenizer) ReadStreamData(length int) ([]byte, error) {
+	if tk, _ := pr.LastToken(); !tk.IsOther("stream") {
+		return nil, fmt.Errorf("expected stream before stream data, got %v", tk)
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("invalid stream length %d", length)
+	}
+	start := pr.streamPos()
+	if length > maxInt-start {
+		return nil, fmt.Errorf("invalid stream length %d", length)
+	}
+	end := start + length
+	if !pr.growTo(end) {
+		return nil, fmt.Errorf("stream data truncated: expected %d bytes, got %d", length, len(pr.data)-start)
+	}
+	out := copyBytes(pr.data[start:end])
+	pr.setPosition(end)
+	return out, nil
+}
+
+// ReadStreamWithResolver reads a stream dictionary, which must start at the next token,
+// followed by the 'stream' keyword, and returns the stream content, whose length is
+// given by the /Length entry.
+// If /Length is an indirect reference, `resolve` is called to obtain its value.
+// The tokenizer is positionned after the data, that is before 'endstream'.
+func (pr *Tokenizer) ReadStreamWithResolver(resolve func(num, gen int) (int, error)) ([]byte, error) {
+	entries, err := pr.ReadDictEntries()
+	if err != nil {
+		return nil, err
+	}
+	length := -1
+	for _, entry := range entries {
+		if entry.Key != "Length" {
+			continue
+		}
+		switch value := entry.ValueTokens; len(value) {
+		case 1:
+			if length, err = value[0].Int(); err != nil {
+				return nil, fmt.Errorf("invalid stream length: %s", err)
+			}
+		case 3: // N G R
+			num, err := value[0].Int()
+			if err != nil {
+				return nil, err
+			}
+			gen, err := value[1].Int()
+			if err != nil {
+				return nil, err
+			}
+			if length, err = resolve(num, gen); err != nil {
+				return nil, fmt.Errorf("resolving stream length %d %d R: %s", num, gen, err)
+			}
+		default:
+			return nil, fmt.Errorf("invalid stream length %v", value)
+		}
+	}
+	if length == -1 {
+		return nil, errors.New("missing stream length")
+	}
+	tk, err := pr.NextToken()
+	if err != nil {
+		return nil, err
+	}
+	if !tk.IsOther("stream") {
+		return nil, fmt.Errorf("expected stream, got %v", tk)
+	}
+	return pr.ReadStreamData(length)
+}
@@ -0,0 +1,368 @@
+package tokenizer
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReadDictEntries(t *testing.T) {
+	tk := NewTokenizer([]byte("<< /A [1 2] /B (x) >>"))
+	entries, err := tk.ReadDictEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []DictEntry{
+		{Key: "A", ValueTokens: []Token{
+			{Kind: StartArray},
+			{Kind: Integer, Value: []byte("1")},
+			{Kind: Integer, Value: []byte("2")},
+			{Kind: EndArray},
+		}},
+		{Key: "B", ValueTokens: []Token{{Kind: String, Value: []byte("x")}}},
+	}
+	if !reflect.DeepEqual(entries, exp) {
+		t.Fatalf("expected %v, got %v", exp, entries)
+	}
+
+	tk = NewTokenizer([]byte("<< /Root 1 0 R /D << /E [/F] >> /Size 4 >>"))
+	entries, err = tk.ReadDictEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || len(entries[0].ValueTokens) != 3 || len(entries[1].ValueTokens) != 6 {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+
+	for _, input := range []string{"[1 2]", "<< /A", "<< 1 2 >>", "<< /A >>"} {
+		tk = NewTokenizer([]byte(input))
+		if _, err = tk.ReadDictEntries(); err == nil {
+			t.Errorf("expected error for %s", input)
+		}
+	}
+}
+
+func TestSkipObject(t *testing.T) {
+	input := []byte(`1 0 obj
+<< /Type /Catalog >>
+endobj
+2 0 obj
+(endobj)
+endobj
+3 0 obj
+<< /Length 20 >>
+stream
+endobj 4 0 obj binary
+endstream
+endobj
+4 0 obj
+[1 2]
+endobj`)
+	for _, tk := range []*Tokenizer{NewTokenizer(input), NewTokenizerFromReader(bytes.NewReader(input))} {
+		if err := tk.SkipObject(3, 0); err == nil {
+			t.Fatal("expected error for wrong object")
+		}
+		if p := tk.CurrentPosition(); p != 0 {
+			t.Fatalf("expected unchanged position, got %d", p)
+		}
+		if err := tk.SkipObject(1, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := tk.SkipObject(2, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := tk.SkipObject(3, 0); err != nil {
+			t.Fatal(err)
+		}
+		got, err := tk.readAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 8 || string(got[0].Value) != "4" || !got[2].IsOther("obj") {
+			t.Fatalf("unexpected tokens after skip: %v", got)
+		}
+	}
+
+	tk := NewTokenizer([]byte("3 0 obj << >> stream\nabc"))
+	if err := tk.SkipObject(3, 0); err == nil {
+		t.Fatal("expected error for missing endstream")
+	}
+}
+
+func TestSplitObjects(t *testing.T) {
+	input := []byte(`%PDF-1.7
+1 0 obj
+<< /Type /Catalog /Pages 2 0 R >>
+endobj
+2 0 obj
+<< /Length 17 >>
+stream
+1 0 obj endobj 
+endstream
+endobj
+xref
+0 3
+0000000000 65535 f
+trailer
+<< /Root 1 0 R >>`)
+	objs, err := SplitObjects(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("expected 2 objects, got %d", len(objs))
+	}
+	if objs[0].Num != 1 || objs[0].Gen != 0 || len(objs[0].Tokens) != 8 {
+		t.Errorf("unexpected object %v", objs[0])
+	}
+	exp := []Token{
+		{Kind: StartDic},
+		{Kind: Name, Value: []byte("Length")},
+		{Kind: Integer, Value: []byte("17")},
+		{Kind: EndDic},
+		{Kind: Other, Value: []byte("stream")},
+	}
+	if objs[1].Num != 2 || !reflect.DeepEqual(objs[1].Tokens, exp) {
+		t.Errorf("expected %v, got %v", exp, objs[1])
+	}
+
+	for _, input := range []string{"1 0 obj << >>", "1 0 obj << >> stream\n", "1 0 obj stream\nendstream 4"} {
+		if _, err = SplitObjects([]byte(input)); err == nil {
+			t.Errorf("expected error for %s", input)
+		}
+	}
+}
+
+func TestReadProcRaw(t *testing.T) {
+	input := "/Sub {dup 0 gt { 1 sub } %comment }\n { pop (}) } ifelse} def"
+	for _, tk := range []*Tokenizer{NewTokenizer([]byte(input)), NewTokenizerFromReader(strings.NewReader(input))} {
+		tk.NextToken()
+		raw, err := tk.ReadProcRaw()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := "{dup 0 gt { 1 sub } %comment }\n { pop (}) } ifelse}"; string(raw) != exp {
+			t.Errorf("expected %s, got %s", exp, raw)
+		}
+		next, _ := tk.NextToken()
+		if !next.IsOther("def") {
+			t.Errorf("expected def, got %v", next)
+		}
+	}
+
+	for _, input := range []string{"/a", "{ 1 { 2 }"} {
+		if _, err := NewTokenizer([]byte(input)).ReadProcRaw(); err == nil {
+			t.Errorf("expected error for %s", input)
+		}
+	}
+}
+
+func TestMaxProcNesting(t *testing.T) {
+	input := []byte(strings.Repeat("{", 50) + strings.Repeat("}", 50))
+
+	tk := NewTokenizer(input)
+	if _, err := tk.ReadProcRaw(); err != nil {
+		t.Fatal(err)
+	}
+	tk.MaxProcNesting = 20
+	tk.Reset(input)
+	if _, err := tk.ReadProcRaw(); err == nil {
+		t.Fatal("expected error for deeply nested procedures")
+	}
+	tk.Reset(append([]byte("<< /A [ "), input...))
+	tk.NextToken()
+	tk.NextToken()
+	if _, err := tk.readValue(); err == nil {
+		t.Fatal("expected error for deeply nested procedures")
+	}
+
+	// the default limit
+	tk.MaxProcNesting = 0
+	tk.Reset([]byte(strings.Repeat("{", 2000) + strings.Repeat("}", 2000)))
+	if _, err := tk.ReadProcRaw(); err == nil {
+		t.Fatal("expected error for deeply nested procedures")
+	}
+}
+
+func TestReadInlineImageData(t *testing.T) {
+	data := []byte{0x00, 'E', 'I', 0xff, ' ', 'E', 'I', 'x', '\n', 'E', 'I', '5'} // EI-like bytes
+	input := append([]byte("q BI /W 12 /H 1 /BPC 8 /CS /G ID "), data...)
+	input = append(input, "\nEI Q"...)
+	for _, tk := range []*Tokenizer{
+		NewTokenizer(input),
+		NewTokenizerFromReader(bytes.NewReader(input), WithReaderBufferSize(8)),
+	} {
+		for {
+			tok, err := tk.NextToken()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tok.IsOther("ID") {
+				break
+			}
+		}
+		got, err := tk.ReadInlineImageData()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("expected %v, got %v", data, got)
+		}
+		if tok, _ := tk.NextToken(); !tok.IsOther("Q") {
+			t.Errorf("expected Q, got %v", tok)
+		}
+	}
+
+	tk := NewTokenizer([]byte("BI /W 1 ID abcdEIx"))
+	if _, err := tk.ReadInlineImageData(); err == nil {
+		t.Error("expected error before ID")
+	}
+	for i := 0; i < 5; i++ {
+		tk.NextToken()
+	}
+	if _, err := tk.ReadInlineImageData(); err == nil {
+		t.Error("expected error for missing EI")
+	}
+}
+
+func TestReadReferenceArray(t *testing.T) {
+	refs, err := NewTokenizer([]byte("[1 0 R 2 0 R]")).ReadReferenceArray()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []Reference{{1, 0}, {2, 0}}; !reflect.DeepEqual(refs, exp) {
+		t.Errorf("expected %v, got %v", exp, refs)
+	}
+
+	refs, err = NewTokenizer([]byte("[\n]")).ReadReferenceArray()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 0 {
+		t.Errorf("expected no references, got %v", refs)
+	}
+
+	for _, input := range []string{
+		"<< >>",
+		"[1 0 R 2 0]",
+		"[1 0 R 2]",
+		"[1 0 R /A 0 R]",
+		"[1 0 R",
+		"[1.5 0 R]",
+	} {
+		if _, err = NewTokenizer([]byte(input)).ReadReferenceArray(); err == nil {
+			t.Errorf("expected error for %s", input)
+		}
+	}
+}
+
+func TestReadStreamData(t *testing.T) {
+	content := strings.Repeat("0 0 m 10 10 l S\n", 100)
+	for _, sep := range []string{"\r\n", "\n"} {
+		input := "<< /Length " + strconv.Itoa(len(content)) + " >>\nstream" + sep + content + "\nendstream\nendobj"
+		for _, tk := range []*Tokenizer{
+			NewTokenizer([]byte(input)),
+			NewTokenizerFromReader(strings.NewReader(input)),
+		} {
+			entries, err := tk.ReadDictEntries()
+			if err != nil {
+				t.Fatal(err)
+			}
+			length, _ := entries[0].ValueTokens[0].Int()
+			if tok, _ := tk.NextToken(); !tok.IsOther("stream") {
+				t.Fatalf("expected stream, got %v", tok)
+			}
+			data, err := tk.ReadStreamData(length)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != content {
+				t.Errorf("expected %d bytes, got %d", len(content), len(data))
+			}
+			if tok, _ := tk.NextToken(); !tok.IsOther("endstream") {
+				t.Errorf("expected endstream, got %v", tok)
+			}
+		}
+	}
+
+	tk := NewTokenizer([]byte("stream\nabc"))
+	if _, err := tk.ReadStreamData(3); err == nil {
+		t.Error("expected error before stream")
+	}
+	tk.NextToken()
+	if _, err := tk.ReadStreamData(10); err == nil {
+		t.Error("expected error for truncated data")
+	}
+	if data, err := tk.ReadStreamData(3); err != nil || string(data) != "abc" {
+		t.Errorf("expected abc, got %s (%v)", data, err)
+	}
+
+	// absurd lengths
+	input := "stream\nabcdef"
+	for _, length := range []int{maxInt, maxInt - 7, 1 << 40} {
+		for _, tk := range []*Tokenizer{
+			NewTokenizer([]byte(input)),
+			NewTokenizerFromReader(strings.NewReader(input)),
+		} {
+			tk.NextToken()
+			if _, err := tk.ReadStreamData(length); err == nil {
+				t.Errorf("expected error for length %d", length)
+			}
+		}
+	}
+}
+
+func TestReadStreamWithResolver(t *testing.T) {
+	input := "<< /Filter /FlateDecode /Length 8 0 R >>\nstream\r\n0123endstream\nendstream"
+	resolve := func(num, gen int) (int, error) {
+		if num == 8 && gen == 0 {
+			return 13, nil
+		}
+		return 0, errors.New("unknown object")
+	}
+	tk := NewTokenizer([]byte(input))
+	data, err := tk.ReadStreamWithResolver(resolve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "0123endstream" {
+		t.Errorf("expected 0123endstream, got %q", data)
+	}
+	if tok, _ := tk.NextToken(); !tok.IsOther("endstream") {
+		t.Errorf("expected endstream, got %v", tok)
+	}
+
+	// direct length
+	data, err = NewTokenizer([]byte("<< /Length 2 >> stream\nab\nendstream")).ReadStreamWithResolver(resolve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "ab" {
+		t.Errorf("expected ab, got %q", data)
+	}
+
+	for _, input := range []string{
+		"<< /Length 9 0 R >> stream\nab\nendstream",
+		"<< /Filter /A >> stream\nab\nendstream",
+		"<< /Length 2 >> endobj",
+		"<< /Length [2] >> stream\nab\nendstream",
+	} {
+		if _, err = NewTokenizer([]byte(input)).ReadStreamWithResolver(resolve); err == nil {
+			t.Errorf("expected error for %s", input)
+		}
+	}
+	// absurd resolved length
+	huge := func(num, gen int) (int, error) { return maxInt, nil }
+	input = "<< /Length 8 0 R >> stream\nab\nendstream"
+	for _, tk := range []*Tokenizer{
+		NewTokenizer([]byte(input)),
+		NewTokenizerFromReader(strings.NewReader(input)),
+	} {
+		if _, err = tk.ReadStreamWithResolver(huge); err == nil {
+			t.Error("expected error for huge length")
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package tokenizer
+
+// OperatorArity returns the number of operands expected by
+// the given content stream operator (see the Table A.1 of the PDF specification).
+// Unknown operators, and operators with a variable number of
+// operands (SC, SCN, sc and scn) return false.
+func OperatorArity(op string) (int, bool) {
+	n, ok := operatorsArity[op]
+	return n, ok
+}
+
+var operatorsArity = map[string]int{
+	// general graphics state
+	"w": 1, "J": 1, "j": 1, "M": 1, "d": 2, "ri": 1, "i": 1, "gs": 1,
+	// special graphics state
+	"q": 0, "Q": 0, "cm": 6,
+	// path construction
+	"m": 2, "l": 2, "c": 6, "v": 4, "y": 4, "h": 0, "re": 4,
+	// path painting
+	"S": 0, "s": 0, "f": 0, "F": 0, "f*": 0, "B": 0, "B*": 0, "b": 0, "b*": 0, "n": 0,
+	// clipping paths
+	"W": 0, "W*": 0,
+	// text objects
+	"BT": 0, "ET": 0,
+	// text state
+	"Tc": 1, "Tw": 1, "Tz": 1, "TL": 1, "Tf": 2, "Tr": 1, "Ts": 1,
+	// text positioning
+	"Td": 2, "TD": 2, "Tm": 6, "T*": 0,
+	// text showing
+	"Tj": 1, "TJ": 1, "'": 1, "\"": 3,
+	// Type 3 fonts
+	"d0": 2, "d1": 6,
+	// color
+	"CS": 1, "cs": 1, "G": 1, "g": 1, "RG": 3, "rg": 3, "K": 4, "k": 4,
+	// shading patterns
+	"sh": 1,
+	// inline images
+	"BI": 0, "ID": 0, "EI": 0,
+	// XObjects
+	"Do": 1,
+	// marked content
+	"MP": 1, "DP": 2, "BMC": 1, "BDC": 2, "EMC": 0,
+	// compatibility
+	"BX": 0, "EX": 0,
+}
@@ -0,0 +1,24 @@
+package tokenizer
+
+import "testing"
+
+func TestOperatorArity(t *testing.T) {
+	for _, test := range []struct {
+		op string
+		n  int
+		ok bool
+	}{
+		{"cm", 6, true},
+		{"Tf", 2, true},
+		{"re", 4, true},
+		{"Q", 0, true},
+		{"\"", 3, true},
+		{"scn", 0, false},
+		{"xyz", 0, false},
+	} {
+		n, ok := OperatorArity(test.op)
+		if n != test.n || ok != test.ok {
+			t.Errorf("%s: expected (%d, %v), got (%d, %v)", test.op, test.n, test.ok, n, ok)
+		}
+	}
+}
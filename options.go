@@ -0,0 +1,177 @@
+package tokenizer
+
+// config stores the options of a Tokenizer.
+type config struct {
+	// MaxTokens, if strictly positive, limits the number of tokens
+	// returned by `NextToken`, which returns an error once exceeded.
+	// It may be used to bound the work done on untrusted input.
+	MaxTokens int
+
+	// CoalesceSignedNumbers is a lenient option which merges
+	// a lone '-' or '+' with a following number, as
+	// emitted by some broken writers (like in "- 5").
+	CoalesceSignedNumbers bool
+
+	// StrictNumbers makes malformed numbers with
+	// several decimal points (like 1.2.3) an error, instead of
+	// splitting them into several tokens.
+	StrictNumbers bool
+
+	// Strict enforces the PDF syntax, rejecting the constructs
+	// only valid in PostScript files (procedures, radix numbers and charstrings),
+	// names longer than 127 bytes (see Annex C.1 - Architectural limits)
+	// and names containing (or ended by) a null byte.
+	Strict bool
+
+	// StrictHexLength rejects hex strings with an odd number of digits,
+	// instead of completing them with a final 0, as
+	// specified in 7.3.4.3 - Hexadecimal Strings.
+	StrictHexLength bool
+
+	// MaxProcNesting limits the nesting of procedures
+	// accepted by the methods reading a whole procedure, like `ReadProcRaw`.
+	// If zero, a generous default (1000) is used.
+	MaxProcNesting int
+
+	// EmitComments makes the tokenizer return comments as
+	// `Comment` tokens, whose value is the content of the comment,
+	// without the leading % and the final EOL.
+	// Note that most of the higher level methods (like `ReadDictEntries`)
+	// don't expect Comment tokens.
+	EmitComments bool
+
+	// RawStrings disables the processing of escape sequences
+	// and EOL in literal strings: the value of String tokens is then
+	// the source content between the outer parenthesis.
+	// See `Token.DecodedValue` to process them afterwards.
+	RawStrings bool
+
+	// MaxTokenSize, if strictly positive, limits the size (in bytes)
+	// of names, strings, hex strings, comments and keywords:
+	// an error is returned as soon as it is exceeded.
+	// It may be used to bound the memory used on untrusted input.
+	MaxTokenSize int
+
+	// ParseNumberHook, if not nil, is called before the default number parsing
+	// with the raw bytes of the next regular token (numbers and keywords).
+	// If it returns true, its token is used.
+	// The raw slice must not be retained.
+	ParseNumberHook func(raw []byte) (Token, bool)
+
+	// ReaderBufferSize is the number of bytes requested to the source
+	// when the internal buffer is grown, in reader mode.
+	// If zero, 1024 is used.
+	ReaderBufferSize int
+
+	// SkipBOM, if true, ignores the byte order marks (UTF-8, UTF-16 BE and LE)
+	// found before a token, not only at the start of the input.
+	SkipBOM bool
+
+	// CaptureTrivia, if true, records the white spaces and comments
+	// found between tokens (see `Tokenizer.Trivia`).
+	CaptureTrivia bool
+
+	// CharStringCommands are the keywords followed by binary charstring data
+	// in Type1 fonts. If empty, the standard RD and -| are used.
+	// Note that the keywords closing the entries (like ND, |-, NP or |)
+	// are not special and are returned as Other tokens.
+	CharStringCommands []string
+
+	// ZeroCopy, if true, avoids copying the input for the values of
+	// names, numbers, keywords, comments, charstrings and raw strings (see `RawStrings`):
+	// they point into the input instead.
+	// This is unsafe if the input is modified or reused while the tokens are still in use:
+	// in reader mode, the values are invalidated by `ResetFromReader`.
+	ZeroCopy bool
+
+	// ReuseValues, if true, builds the token values in internal buffers,
+	// reused by the next tokenizations : the value of a token returned
+	// by `NextToken` is only valid until the next call to `NextToken`
+	// (or any other method advancing the tokenizer), and must be copied to be kept.
+	// This saves allocations when processing large inputs in tight loops.
+	ReuseValues bool
+}
+
+// Option is a configuration option for a Tokenizer,
+// to be used with `NewTokenizer` or `NewTokenizerFromReader`.
+type Option func(*config)
+
+// WithMaxTokens sets the `MaxTokens` option.
+func WithMaxTokens(n int) Option {
+	return func(c *config) { c.MaxTokens = n }
+}
+
+// WithCoalesceSignedNumbers sets the `CoalesceSignedNumbers` option.
+func WithCoalesceSignedNumbers() Option {
+	return func(c *config) { c.CoalesceSignedNumbers = true }
+}
+
+// WithStrictNumbers sets the `StrictNumbers` option.
+func WithStrictNumbers() Option {
+	return func(c *config) { c.StrictNumbers = true }
+}
+
+// WithStrict sets the `Strict` option.
+func WithStrict() Option {
+	return func(c *config) { c.Strict = true }
+}
+
+// WithStrictHexLength sets the `StrictHexLength` option.
+func WithStrictHexLength() Option {
+	return func(c *config) { c.StrictHexLength = true }
+}
+
+// WithMaxProcNesting sets the `MaxProcNesting` option.
+func WithMaxProcNesting(n int) Option {
+	return func(c *config) { c.MaxProcNesting = n }
+}
+
+// WithComments sets the `EmitComments` option.
+func WithComments() Option {
+	return func(c *config) { c.EmitComments = true }
+}
+
+// WithRawStrings sets the `RawStrings` option.
+func WithRawStrings() Option {
+	return func(c *config) { c.RawStrings = true }
+}
+
+// WithMaxTokenSize sets the `MaxTokenSize` option.
+func WithMaxTokenSize(n int) Option {
+	return func(c *config) { c.MaxTokenSize = n }
+}
+
+// WithParseNumberHook sets the `ParseNumberHook` option.
+func WithParseNumberHook(hook func(raw []byte) (Token, bool)) Option {
+	return func(c *config) { c.ParseNumberHook = hook }
+}
+
+// WithReaderBufferSize sets the `ReaderBufferSize` option.
+func WithReaderBufferSize(n int) Option {
+	return func(c *config) { c.ReaderBufferSize = n }
+}
+
+// WithSkipBOM sets the `SkipBOM` option.
+func WithSkipBOM() Option {
+	return func(c *config) { c.SkipBOM = true }
+}
+
+// WithCaptureTrivia sets the `CaptureTrivia` option.
+func WithCaptureTrivia() Option {
+	return func(c *config) { c.CaptureTrivia = true }
+}
+
+// WithCharStringCommands sets the `CharStringCommands` option.
+func WithCharStringCommands(commands ...string) Option {
+	return func(c *config) { c.CharStringCommands = commands }
+}
+
+// WithZeroCopy sets the `ZeroCopy` option.
+func WithZeroCopy() Option {
+	return func(c *config) { c.ZeroCopy = true }
+}
+
+// WithReuseValues sets the `ReuseValues` option.
+func WithReuseValues() Option {
+	return func(c *config) { c.ReuseValues = true }
+}
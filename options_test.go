@@ -0,0 +1,478 @@
+package tokenizer
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestOptions(t *testing.T) {
+	input := "%PDF-1.7\n- 5 (a\\053) {1}"
+
+	tks, err := NewTokenizer([]byte(input), WithComments(), WithCoalesceSignedNumbers(), WithRawStrings()).readAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []Token{
+		{Kind: Comment, Value: []byte("PDF-1.7")},
+		{Kind: Integer, Value: []byte("-5")},
+		{Kind: String, Value: []byte("a\\053")},
+		{Kind: StartProc},
+		{Kind: Integer, Value: []byte("1")},
+		{Kind: EndProc},
+	}
+	if !reflect.DeepEqual(tks, exp) {
+		t.Errorf("expected %v, got %v", exp, tks)
+	}
+
+	// no options
+	tks1, err := Tokenize([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tks2, err := NewTokenizerFromReader(strings.NewReader(input)).readAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tks1, tks2) || len(tks1) != 6 {
+		t.Errorf("unexpected tokens %v %v", tks1, tks2)
+	}
+
+	// options are applied to the first tokens
+	tk := NewTokenizerFromReader(strings.NewReader(input), WithStrict(), WithComments())
+	if next, _ := tk.NextToken(); next.Kind != Comment {
+		t.Errorf("expected Comment, got %v", next)
+	}
+	if _, err = tk.readAll(); err == nil {
+		t.Error("expected error for procedure in strict mode")
+	}
+
+	// options are kept on Reset and shared by SubTokenizer
+	tk.Reset([]byte("1 2 3 4"))
+	sub := tk.SubTokenizer(0, 20)
+	if !sub.Strict || !sub.EmitComments {
+		t.Error("expected options in SubTokenizer")
+	}
+
+	tk = NewTokenizer([]byte("1 2 3 4"), WithMaxTokens(2), WithStrictNumbers(), WithStrictHexLength(), WithMaxProcNesting(4))
+	if tk.MaxTokens != 2 || !tk.StrictNumbers || !tk.StrictHexLength || tk.MaxProcNesting != 4 {
+		t.Errorf("unexpected config %v", tk.config)
+	}
+	if _, err = tk.readAll(); err == nil {
+		t.Error("expected error for too many tokens")
+	}
+}
+
+func TestMaxTokenSize(t *testing.T) {
+	const MB = 1 << 20
+	big := strings.Repeat("a", 10*MB)
+	for _, input := range []string{
+		"(" + big + ")",
+		"/" + big,
+		"<" + strings.Repeat("ab", 5*MB) + ">",
+		big,
+	} {
+		tks, err := Tokenize([]byte(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tks) != 1 {
+			t.Fatalf("expected 1 token, got %d", len(tks))
+		}
+
+		_, err = NewTokenizer([]byte(input), WithMaxTokenSize(MB)).readAll()
+		if err == nil {
+			t.Fatal("expected error for too large token")
+		}
+		if !strings.Contains(err.Error(), "maximum size") {
+			t.Errorf("unexpected error %s", err)
+		}
+	}
+
+	_, err := NewTokenizer([]byte("%"+strings.Repeat("c", 100)), WithComments(), WithMaxTokenSize(10)).readAll()
+	if err == nil {
+		t.Fatal("expected error for too large comment")
+	}
+	// limit is inclusive
+	if _, err = NewTokenizer([]byte("/abcd (abcd) <61626364> abcd"), WithMaxTokenSize(4)).readAll(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseNumberHook(t *testing.T) {
+	hook := func(raw []byte) (Token, bool) {
+		if string(raw) == "NaN" {
+			return Token{Kind: Float, Value: []byte("NaN")}, true
+		}
+		return Token{}, false
+	}
+	tks, err := NewTokenizer([]byte("[NaN 1 -2.5 NaNx /NaN]"), WithParseNumberHook(hook)).readAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []Token{
+		{Kind: StartArray},
+		{Kind: Float, Value: []byte("NaN")},
+		{Kind: Integer, Value: []byte("1")},
+		{Kind: Float, Value: []byte("-2.5")},
+		{Kind: Other, Value: []byte("NaNx")},
+		{Kind: Name, Value: []byte("NaN")},
+		{Kind: EndArray},
+	}
+	if !reflect.DeepEqual(tks, exp) {
+		t.Errorf("expected %v, got %v", exp, tks)
+	}
+	f, err := tks[1].Float()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsNaN(f) {
+		t.Errorf("expected NaN, got %v", f)
+	}
+
+	// the bytes read for the hook are limited as well
+	_, err = NewTokenizer([]byte("[ "+strings.Repeat("9", 100)+" ]"), WithParseNumberHook(hook), WithMaxTokenSize(10)).readAll()
+	if te, ok := err.(*TokenizerError); !ok || te.Pos != 2 {
+		t.Errorf("expected TokenizerError at 2, got %v", err)
+	}
+}
+
+// countingReader counts the calls to Read
+type countingReader struct {
+	src     io.Reader
+	nbReads int
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	cr.nbReads++
+	return cr.src.Read(p)
+}
+
+func TestReaderBufferSize(t *testing.T) {
+	input := []byte(strings.Repeat("<< /Type /Page /MediaBox [0 0 612 792] >>\n", 1000))
+	exp, err := Tokenize(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nbReads []int
+	for _, size := range []int{0, 16, 64 << 10} {
+		cr := &countingReader{src: bytes.NewReader(input)}
+		got, err := NewTokenizerFromReader(cr, WithReaderBufferSize(size)).readAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, exp) {
+			t.Fatalf("unexpected tokens with buffer size %d", size)
+		}
+		nbReads = append(nbReads, cr.nbReads)
+	}
+	if !(nbReads[1] > nbReads[0] && nbReads[0] > nbReads[2]) {
+		t.Errorf("unexpected number of reads %v", nbReads)
+	}
+}
+
+func BenchmarkReaderBufferSize(b *testing.B) {
+	input := []byte(strings.Repeat("<< /Type /Page /MediaBox [0 0 612 792] >>\n", 10000))
+	for _, size := range []int{1 << 10, 64 << 10} {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			nbReads := 0
+			for i := 0; i < b.N; i++ {
+				cr := &countingReader{src: bytes.NewReader(input)}
+				tk := NewTokenizerFromReader(cr, WithReaderBufferSize(size))
+				for {
+					t, err := tk.NextToken()
+					if err != nil {
+						b.Fatal(err)
+					}
+					if t.Kind == EOF {
+						break
+					}
+				}
+				nbReads += cr.nbReads
+			}
+			b.ReportMetric(float64(nbReads)/float64(b.N), "reads/op")
+		})
+	}
+}
+
+func TestSkipBOM(t *testing.T) {
+	input := "\xef\xbb\xbf%PDF-1.7\n<< /T \xfe\xff<FEFF0041> /U\xef\xbb\xbf(a) \xff\xfe[1] >>"
+	exp := []Token{
+		{Kind: StartDic},
+		{Kind: Name, Value: []byte("T")},
+		{Kind: StringHex, Value: []byte{0xfe, 0xff, 0, 'A'}},
+		{Kind: Name, Value: []byte("U\xef\xbb\xbf")}, // not before a token
+		{Kind: String, Value: []byte("a")},
+		{Kind: StartArray},
+		{Kind: Integer, Value: []byte("1")},
+		{Kind: EndArray},
+		{Kind: EndDic},
+	}
+	for _, tk := range []*Tokenizer{
+		NewTokenizer([]byte(input), WithSkipBOM()),
+		NewTokenizerFromReader(strings.NewReader(input), WithSkipBOM()),
+	} {
+		tks, err := tk.readAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tks, exp) {
+			t.Errorf("expected %v, got %v", exp, tks)
+		}
+	}
+
+	// without the option, the BOM is an Other token
+	tks, err := Tokenize([]byte("\xfe\xff<41>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tks) != 2 || tks[0].Kind != Other {
+		t.Errorf("unexpected tokens %v", tks)
+	}
+}
+
+func TestCaptureTrivia(t *testing.T) {
+	input := "<<\n  /Type /Page % a page\n  /Count 3\n>>\n"
+	tk := NewTokenizer([]byte(input), WithCaptureTrivia())
+	tks, err := tk.readAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []Trivia{
+		{Pos: 2, Value: []byte("\n  ")},
+		{Pos: 10, Value: []byte(" ")},
+		{Pos: 16, Value: []byte(" % a page\n  ")},
+		{Pos: 34, Value: []byte(" ")},
+		{Pos: 36, Value: []byte("\n")},
+		{Pos: 39, Value: []byte("\n")},
+	}
+	if got := tk.Trivia(); !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+
+	// the input may be reconstructed
+	var rebuilt []byte
+	pos := 0
+	for _, tr := range tk.Trivia() {
+		rebuilt = append(rebuilt, input[pos:tr.Pos]...)
+		rebuilt = append(rebuilt, tr.Value...)
+		pos = tr.Pos + len(tr.Value)
+	}
+	if string(rebuilt) != input || len(tks) != 6 {
+		t.Errorf("unexpected reconstruction %q", rebuilt)
+	}
+
+	// jumping backward does not duplicate trivia
+	tk.SetPosition(16)
+	if _, err = tk.readAll(); err != nil {
+		t.Fatal(err)
+	}
+	if got := tk.Trivia(); !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+
+	if tk = NewTokenizer([]byte(input)); len(tk.Trivia()) != 0 {
+		t.Error("trivia should not be captured by default")
+	}
+}
+
+func TestCharStringTerminators(t *testing.T) {
+	input := "/Subrs 2 array\ndup 0 4 RD abcd NP\ndup 1 3 -| efg |\nND\n" +
+		"/CharStrings 1 dict dup begin\n/.notdef 4 RD \x8d\x12)\x05 ND\n/a 2 -| (} |-\nend"
+	tks, err := Tokenize([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var charstrings, closing []string
+	for _, tk := range tks {
+		switch {
+		case tk.Kind == CharString:
+			charstrings = append(charstrings, string(tk.Command())+":"+string(tk.Value))
+		case tk.IsOther("ND"), tk.IsOther("|-"), tk.IsOther("NP"), tk.IsOther("|"):
+			closing = append(closing, string(tk.Value))
+		}
+	}
+	if exp := []string{"RD:abcd", "-|:efg", "RD:\x8d\x12)\x05", "-|:(}"}; !reflect.DeepEqual(charstrings, exp) {
+		t.Errorf("expected %q, got %q", exp, charstrings)
+	}
+	if exp := []string{"NP", "|", "ND", "ND", "|-"}; !reflect.DeepEqual(closing, exp) {
+		t.Errorf("expected %v, got %v", exp, closing)
+	}
+
+	// custom commands
+	tks, err = NewTokenizer([]byte("/a 3 -rd- xyz ND 2 RD"), WithCharStringCommands("-rd-")).readAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tks) != 6 || tks[2].Kind != CharString || string(tks[2].Value) != "xyz" || !tks[5].IsOther("RD") {
+		t.Errorf("unexpected tokens %v", tks)
+	}
+}
+
+// aliases returns true if `b` points into `data`
+func aliases(b, data []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	start := uintptr(unsafe.Pointer(&data[0]))
+	p := uintptr(unsafe.Pointer(&b[0]))
+	return start <= p && p < start+uintptr(len(data))
+}
+
+func TestZeroCopy(t *testing.T) {
+	input := []byte("%comment\n<< /Type /Page /Count 3 /MediaBox [0 0 612.5 1e3] /Na#20me (st\\)r) <41> >> true 4 RD abcd")
+	exp, err := NewTokenizer(input, WithComments(), WithRawStrings()).readAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewTokenizer(input, WithComments(), WithRawStrings(), WithZeroCopy()).readAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(exp) {
+		t.Fatalf("expected %d tokens, got %d", len(exp), len(got))
+	}
+	for i, tk := range got {
+		if !tk.Equal(exp[i]) {
+			t.Errorf("expected %v, got %v", exp[i], tk)
+		}
+		if aliases(exp[i].Value, input) {
+			t.Errorf("%v should be a copy", exp[i])
+		}
+		// hex strings are decoded
+		if shouldAlias := tk.Kind != StringHex && len(tk.Value) != 0; aliases(tk.Value, input) != shouldAlias {
+			t.Errorf("%v: expected aliasing %v", tk, shouldAlias)
+		}
+	}
+
+	// appending to a value does not corrupt the input
+	_ = append(got[2].Value, "XXX"...)
+	if string(input[13:21]) != "Type /Pa" {
+		t.Errorf("input modified: %s", input)
+	}
+}
+
+var nameHeavyContent = []byte(strings.Repeat("/GS1 gs /F1 12 Tf /Im1 Do /Span << /MCID 3 /ActualText (x) >> BDC EMC 0.5 0 0 0.5 12.25 100 cm\n", 200))
+
+func BenchmarkZeroCopy(b *testing.B) {
+	for _, zeroCopy := range []bool{false, true} {
+		b.Run(strconv.FormatBool(zeroCopy), func(b *testing.B) {
+			b.ReportAllocs()
+			var opts []Option
+			if zeroCopy {
+				opts = append(opts, WithZeroCopy())
+			}
+			tk := NewTokenizer(nil, opts...)
+			for i := 0; i < b.N; i++ {
+				tk.Reset(nameHeavyContent)
+				for {
+					t, err := tk.NextToken()
+					if err != nil {
+						b.Fatal(err)
+					}
+					if t.Kind == EOF {
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReuseValues(t *testing.T) {
+	input := []byte("<< /Type /Page /T (a\\(b\\)) /H <41 42> /N [1 2.5 -3] >> 1 0 obj (x) endobj /End")
+	exp, err := Tokenize(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := NewTokenizer(input, WithReuseValues())
+	for i := 0; ; i++ {
+		tok, err := tk.NextToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Kind == EOF {
+			break
+		}
+		if i == 13 && !tk.PeekCouldBeObjHeader() {
+			t.Fatal("expected object header")
+		}
+		// values are only valid until the next call
+		if !tok.Equal(exp[i]) {
+			t.Errorf("expected %v, got %v", exp[i], tok)
+		}
+	}
+
+	// repeated lookahead does not modify the cached tokens
+	tk.Reset([]byte("(a) 1 2 obj (b) endobj"))
+	tk.NextToken()
+	for i := 0; i < 6; i++ {
+		if !tk.PeekCouldBeObjHeader() {
+			t.Fatal("expected object header")
+		}
+	}
+	if tok, _ := tk.LastToken(); string(tok.Value) != "a" {
+		t.Errorf("expected a, got %s", tok.Value)
+	}
+	for _, exp := range []string{"1", "2", "obj"} {
+		if tok, _ := tk.NextToken(); string(tok.Value) != exp {
+			t.Errorf("expected %s, got %s", exp, tok.Value)
+		}
+	}
+
+	// the unread token is preserved
+	tk.Reset([]byte("/A (B) /C"))
+	tk.NextToken()
+	tk.NextToken()
+	tk.UnreadToken()
+	if tok, _ := tk.LastToken(); string(tok.Value) != "A" {
+		t.Errorf("expected A, got %s", tok.Value)
+	}
+	if tok, _ := tk.NextToken(); string(tok.Value) != "B" {
+		t.Errorf("expected B, got %s", tok.Value)
+	}
+
+	// the buffers are reused
+	allocs := testing.AllocsPerRun(10, func() {
+		tk.Reset(nameHeavyContent)
+		for {
+			tok, _ := tk.NextToken()
+			if tok.Kind == EOF {
+				break
+			}
+		}
+	})
+	if allocs > 10 {
+		t.Errorf("expected few allocations, got %v", allocs)
+	}
+}
+
+func BenchmarkReuseValues(b *testing.B) {
+	for _, reuse := range []bool{false, true} {
+		b.Run(strconv.FormatBool(reuse), func(b *testing.B) {
+			b.ReportAllocs()
+			var opts []Option
+			if reuse {
+				opts = append(opts, WithReuseValues())
+			}
+			tk := NewTokenizer(nil, opts...)
+			for i := 0; i < b.N; i++ {
+				tk.Reset(nameHeavyContent)
+				for {
+					t, err := tk.NextToken()
+					if err != nil {
+						b.Fatal(err)
+					}
+					if t.Kind == EOF {
+						break
+					}
+				}
+			}
+		})
+	}
+}
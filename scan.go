@@ -0,0 +1,301 @@
+package tokenizer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// FirstBinaryOffset tokenizes `data` until a binary
+// stream (started by 'stream') or inline data (started by 'ID')
+// is found, and returns the start of the binary data and the keyword
+// introducing it.
+// `ok` is false if no binary data is found or if the input is invalid.
+func FirstBinaryOffset(data []byte) (pos int, keyword string, ok bool) {
+	tk := NewTokenizer(data)
+	for {
+		t, err := tk.NextToken()
+		if err != nil || t.Kind == EOF {
+			return 0, "", false
+		}
+		if !t.startsBinary() {
+			continue
+		}
+		keyword = string(t.Value)
+		if keyword == "stream" {
+			return tk.StreamPosition(), keyword, true
+		}
+		// ID is followed by a single white space
+		pos = tk.CurrentPosition()
+		if pos < len(data) && IsAsciiWhitespace(data[pos]) {
+			pos++
+		}
+		return pos, keyword, true
+	}
+}
+
+// LastTokenOf tokenizes `data` and returns its last token,
+// or a zero Token for an empty input.
+// As `Tokenize`, it stops at binary streams and inline data.
+func LastTokenOf(data []byte) (Token, error) {
+	tk := NewTokenizer(data)
+	var last Token
+	for {
+		t, err := tk.NextToken()
+		if err != nil {
+			return Token{}, err
+		}
+		if t.Kind == EOF {
+			return last, nil
+		}
+		last = t
+	}
+}
+
+// EstimateObjectCount returns an approximation of the number of
+// indirect objects in a PDF file, by counting the object headers (N G obj)
+// with a byte level scan (streams are not tokenized).
+// Since stream content is not skipped, the count may be overestimated;
+// updated objects (incremental updates) are also counted several times.
+// The error is currently always nil.
+func EstimateObjectCount(data []byte) (int, error) {
+	count := 0
+	for pos := 0; ; {
+		i := bytes.Index(data[pos:], []byte("obj"))
+		if i == -1 {
+			return count, nil
+		}
+		start := pos + i
+		pos = start + 3
+		if pos < len(data) && !isDelimiter(data[pos]) {
+			continue
+		}
+		if isObjectHeaderEnd(data[:start]) {
+			count++
+		}
+	}
+}
+
+// isObjectHeaderEnd returns true if `data` ends by
+// <digits> <space> <digits> <space>
+func isObjectHeaderEnd(data []byte) bool {
+	i := len(data)
+	for n := 0; n < 2; n++ {
+		// white spaces
+		j := i
+		for j > 0 && IsAsciiWhitespace(data[j-1]) {
+			j--
+		}
+		if j == i {
+			return false
+		}
+		// digits
+		i = j
+		for i > 0 && isDigit(data[i-1]) {
+			i--
+		}
+		if i == j {
+			return false
+		}
+	}
+	return i == 0 || isDelimiter(data[i-1])
+}
+
+// headerSearchLength is the number of bytes
+// scanned by HeaderOffset
+const headerSearchLength = 1024
+
+// HeaderOffset returns the offset of the `%PDF-` header, searched
+// in the first 1024 bytes of `data`.
+// Since offsets in a PDF file are relative to the header,
+// it should be added to them when the file has leading junk.
+func HeaderOffset(data []byte) (int, bool) {
+	if len(data) > headerSearchLength {
+		data = data[:headerSearchLength]
+	}
+	i := bytes.Index(data, []byte("%PDF-"))
+	return i, i != -1
+}
+
+// HasEncryptDict returns true if the last trailer of the PDF file `data`
+// has an /Encrypt entry.
+// The trailer is either the dictionary following the last 'trailer' keyword, or,
+// for files using cross-reference streams, the dictionary of the
+// object pointed by the last 'startxref'.
+// An error is returned if no trailer is found, or if it is invalid.
+func HasEncryptDict(data []byte) (bool, error) {
+	entries, err := readTrailerEntries(data)
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range entries {
+		if entry.Key == "Encrypt" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ReadTrailer locates the last trailer of the PDF file `data`
+// (see `HasEncryptDict`) and returns its entries, mapping
+// each key to the tokens of its value (see `DictEntry.ValueTokens`),
+// like [1 0 R] for references or [[ <01> <02> ]] for the /ID array.
+func ReadTrailer(data []byte) (map[string][]Token, error) {
+	entries, err := readTrailerEntries(data)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string][]Token, len(entries))
+	for _, entry := range entries {
+		out[entry.Key] = entry.ValueTokens
+	}
+	return out, nil
+}
+
+func readTrailerEntries(data []byte) ([]DictEntry, error) {
+	tk := NewTokenizer(data)
+	if i := bytes.LastIndex(data, []byte("trailer")); i != -1 {
+		tk.SetPosition(i + len("trailer"))
+	} else {
+		i = bytes.LastIndex(data, []byte("startxref"))
+		if i == -1 {
+			return nil, errors.New("trailer not found")
+		}
+		tk.SetPosition(i + len("startxref"))
+		offset, err := tk.NextToken()
+		if err != nil {
+			return nil, err
+		}
+		pos, err := offset.Int()
+		if err != nil {
+			return nil, fmt.Errorf("invalid startxref offset: %s", err)
+		}
+		if header, ok := HeaderOffset(data); ok {
+			pos += header
+		}
+		if pos < 0 || pos >= len(data) {
+			return nil, fmt.Errorf("startxref offset %d out of bounds", pos)
+		}
+		// skip the N G obj header of the cross-reference stream
+		tk.SetPosition(pos)
+		for _, kind := range [3]Kind{Integer, Integer, Other} {
+			t, err := tk.NextToken()
+			if err != nil {
+				return nil, err
+			}
+			if t.Kind != kind {
+				return nil, fmt.Errorf("invalid cross-reference stream header at %d", pos)
+			}
+		}
+	}
+	return tk.ReadDictEntries()
+}
+
+// NameError is a problem found in a name by `ValidateNames`.
+type NameError struct {
+	Pos  int    // offset of the '/'
+	Name []byte // the raw name, without '/'
+	Msg  string
+}
+
+func (ne NameError) Error() string {
+	return fmt.Sprintf("invalid name /%s at offset %d: %s", ne.Name, ne.Pos, ne.Msg)
+}
+
+// ValidateNames tokenizes `data` and checks the names, reporting
+// corrupted escapes (#), names longer than 127 bytes and
+// names ended by a NUL byte, as the `Strict` mode does.
+// The validation stops at the first error not related to a name,
+// and, as `Tokenize`, at binary streams and inline data.
+func ValidateNames(data []byte) []NameError {
+	var out []NameError
+	tk := NewTokenizer(data)
+	for {
+		t, err := tk.NextToken()
+		if err != nil {
+			te, ok := err.(*TokenizerError)
+			if !ok {
+				return out
+			}
+			start := nameStart(data, te.Pos)
+			if start == -1 {
+				return out
+			}
+			end := start + 1
+			for end < len(data) && !isDelimiter(data[end]) {
+				end++
+			}
+			out = append(out, NameError{Pos: start, Name: data[start+1 : end], Msg: te.Msg})
+			tk.SetPosition(end)
+			continue
+		}
+		if t.Kind == EOF {
+			return out
+		}
+		if t.Kind != Name {
+			continue
+		}
+		end := tk.CurrentPosition()
+		start := end - len(t.Value) - 1 // escapes are not decoded in Value
+		if L := len(t.Value) - 2*bytes.Count(t.Value, []byte("#")); L > maxNameLength {
+			out = append(out, NameError{Pos: start, Name: t.Value, Msg: fmt.Sprintf("name too long (%d bytes)", L)})
+		}
+		if end < len(data) && data[end] == 0 {
+			out = append(out, NameError{Pos: start, Name: t.Value, Msg: "null byte in name"})
+		}
+	}
+}
+
+// nameStart returns the position of the '/' starting
+// the name containing `pos`, or -1
+func nameStart(data []byte, pos int) int {
+	for i := pos; i >= 0 && i < len(data); i-- {
+		if data[i] == '/' {
+			return i
+		}
+		if isDelimiter(data[i]) {
+			return -1
+		}
+	}
+	return -1
+}
+
+// BinaryRegion is a part of a PDF file containing binary data.
+type BinaryRegion struct {
+	Start, End int    // the data is data[Start:End]
+	Kind       string // "stream" or "inline image"
+}
+
+// FindBinaryRegions tokenizes `data` and returns the location of
+// the content of the streams (between 'stream' and 'endstream')
+// and of the inline images (between 'ID' and 'EI').
+func FindBinaryRegions(data []byte) ([]BinaryRegion, error) {
+	var out []BinaryRegion
+	tk := NewTokenizer(data)
+	for {
+		t, err := tk.NextToken()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case t.Kind == EOF:
+			return out, nil
+		case t.IsOther("stream"):
+			start := tk.StreamPosition()
+			end := tk.indexFrom(start, []byte("endstream"))
+			if end == -1 {
+				return nil, fmt.Errorf("missing endstream for stream at %d", start)
+			}
+			out = append(out, BinaryRegion{Start: start, End: end, Kind: "stream"})
+			tk.SetPosition(end + len("endstream"))
+		case t.IsOther("ID"):
+			start := tk.CurrentPosition() + 1 // single white space after ID
+			end := tk.inlineImageEnd(start)
+			if end == -1 {
+				return nil, fmt.Errorf("missing EI for inline image at %d", start)
+			}
+			out = append(out, BinaryRegion{Start: start, End: end - 1, Kind: "inline image"})
+			tk.SetPosition(end + 2)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package tokenizer
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFirstBinaryOffset(t *testing.T) {
+	for _, test := range []struct {
+		input   string
+		pos     int
+		keyword string
+		ok      bool
+	}{
+		{"<< /Length 4 >>\nstream\r\nabcd\nendstream", 24, "stream", true},
+		{"<< /Length 4 >> stream\nabcd", 23, "stream", true},
+		{"BI /W 4 /H 1 ID abcd EI", 16, "ID", true},
+		{"<< /Length 4 >>", 0, "", false},
+		{"<< (abc >>", 0, "", false},
+	} {
+		pos, keyword, ok := FirstBinaryOffset([]byte(test.input))
+		if pos != test.pos || keyword != test.keyword || ok != test.ok {
+			t.Errorf("expected (%d, %s, %v), got (%d, %s, %v)", test.pos, test.keyword, test.ok, pos, keyword, ok)
+		}
+	}
+}
+
+func TestLastTokenOf(t *testing.T) {
+	tk, err := LastTokenOf([]byte("trailer\n<< /Size 8 /Root 1 0 R >>\nstartxref\n4567\n%%EOF\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tk.Kind != Integer || string(tk.Value) != "4567" {
+		t.Errorf("expected Integer 4567, got %v", tk)
+	}
+
+	tk, err = LastTokenOf([]byte("  %only a comment"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tk.Kind != 0 {
+		t.Errorf("expected zero token, got %v", tk)
+	}
+
+	if _, err = LastTokenOf([]byte("1 (abc")); err == nil {
+		t.Error("expected error for invalid input")
+	}
+}
+
+func TestEstimateObjectCount(t *testing.T) {
+	input := []byte(`%PDF-1.7
+1 0 obj << /Type /Catalog >> endobj
+2 0 obj
+<< /Type /Pages /obj 3 >>
+endobj
+12 5 obj (obj) endobj
+3 0 obj<</Length 3>>stream
+obj
+endstream endobj 4 obj 4 0 objx
+trailer << /Size 5 >>`)
+	n, err := EstimateObjectCount(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Errorf("expected 4 objects, got %d", n)
+	}
+
+	if n, _ = EstimateObjectCount(nil); n != 0 {
+		t.Errorf("expected 0 objects, got %d", n)
+	}
+}
+
+func TestHeaderOffset(t *testing.T) {
+	junk := strings.Repeat("\x00junk\xff\r\n\t ", 5) // 50 bytes
+	for _, test := range []struct {
+		input string
+		pos   int
+		ok    bool
+	}{
+		{"%PDF-1.7\n1 0 obj", 0, true},
+		{junk + "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n", 50, true},
+		{"no header here", -1, false},
+		{strings.Repeat(" ", 2000) + "%PDF-1.7", -1, false},
+	} {
+		pos, ok := HeaderOffset([]byte(test.input))
+		if pos != test.pos || ok != test.ok {
+			t.Errorf("expected (%d, %v), got (%d, %v)", test.pos, test.ok, pos, ok)
+		}
+	}
+}
+
+func TestHasEncryptDict(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		exp   bool
+	}{
+		{"%PDF-1.4\n1 0 obj << >> endobj\ntrailer\n<< /Size 2 /Root 1 0 R >>\nstartxref\n9\n%%EOF", false},
+		{"%PDF-1.4\n1 0 obj << >> endobj\ntrailer\n<< /Size 3 /Root 1 0 R /Encrypt 2 0 R /ID [<01> <02>] >>\nstartxref\n9\n%%EOF", true},
+		// incremental update: only the last trailer is considered
+		{"%PDF-1.4\ntrailer << /Encrypt 2 0 R >>\ntrailer << /Prev 12 /Info << /Title (Encrypt) >> >>", false},
+		// cross-reference stream, with leading junk
+		{"junk%PDF-1.5\n7 0 obj << /Type /XRef /Encrypt << /Filter /Standard >> /Length 0 >> stream\n\nendstream endobj\nstartxref\n9\n%%EOF", true},
+		{"%PDF-1.5\n7 0 obj << /Type /XRef /Length 0 >> stream\n\nendstream endobj\nstartxref\n9\n%%EOF", false},
+	} {
+		got, err := HasEncryptDict([]byte(test.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.exp {
+			t.Errorf("expected %v, got %v", test.exp, got)
+		}
+	}
+
+	for _, input := range []string{
+		"%PDF-1.4\n1 0 obj << >> endobj",
+		"%PDF-1.4\nstartxref\n1000\n%%EOF",
+		"%PDF-1.4\ntrailer (abc",
+	} {
+		if _, err := HasEncryptDict([]byte(input)); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
+
+func TestReadTrailer(t *testing.T) {
+	input := "%PDF-1.4\n1 0 obj << >> endobj\nxref\n0 2\n0000000000 65535 f \n0000000009 00000 n \n" +
+		"trailer\n<< /Size 2 /Root 1 0 R /Info 3 0 R /Prev 1234 /ID [<01> <02>] >>\nstartxref\n35\n%%EOF\n"
+	trailer, err := ReadTrailer([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := func(num, gen string) []Token {
+		return []Token{{Kind: Integer, Value: []byte(num)}, {Kind: Integer, Value: []byte(gen)}, {Kind: Other, Value: []byte("R")}}
+	}
+	exp := map[string][]Token{
+		"Size": {{Kind: Integer, Value: []byte("2")}},
+		"Root": ref("1", "0"),
+		"Info": ref("3", "0"),
+		"Prev": {{Kind: Integer, Value: []byte("1234")}},
+		"ID": {
+			{Kind: StartArray}, {Kind: StringHex, Value: []byte{1}},
+			{Kind: StringHex, Value: []byte{2}}, {Kind: EndArray},
+		},
+	}
+	if !reflect.DeepEqual(trailer, exp) {
+		t.Errorf("expected %v, got %v", exp, trailer)
+	}
+
+	if _, err = ReadTrailer([]byte("%PDF-1.4\n1 0 obj << >> endobj")); err == nil {
+		t.Error("expected error for missing trailer")
+	}
+}
+
+func TestValidateNames(t *testing.T) {
+	long := strings.Repeat("a", 130)
+	input := "<< /Type /Pa#2ge /Ok#20Name /Bad#zzName 1 /" + long + " /N\x00 [/A#4] >> /End"
+	errs := ValidateNames([]byte(input))
+	var got []string
+	for _, err := range errs {
+		got = append(got, fmt.Sprintf("%d:%s", err.Pos, err.Name))
+	}
+	exp := []string{
+		"9:Pa#2ge",
+		"28:Bad#zzName",
+		"42:" + long,
+		"174:N",
+		"179:A#4",
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+
+	if errs = ValidateNames([]byte("<< /Type /Page /Name#20With#23Escapes >>")); len(errs) != 0 {
+		t.Errorf("expected no error, got %v", errs)
+	}
+}
+
+func TestFindBinaryRegions(t *testing.T) {
+	input := "1 0 obj << /Length 5 >> stream\r\n\x00\x01EI\xff\nendstream endobj\n" +
+		"2 0 obj << /Length 40 >> stream\nq BI /W 2 /H 1 /BPC 8 /CS /G ID \x10\x20\nEI Q\nendstream endobj\n" +
+		"trailer << /Size 3 >>"
+	regions, err := FindBinaryRegions([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []BinaryRegion{
+		{Start: 32, End: 38, Kind: "stream"},
+		{Start: 87, End: 127, Kind: "stream"},
+	}
+	if !reflect.DeepEqual(regions, exp) {
+		t.Errorf("expected %v, got %v", exp, regions)
+	}
+
+	// content stream with an inline image
+	content := "q BI /W 2 /H 1 /BPC 8 /CS /G ID \x10\x20\nEI Q"
+	regions, err = FindBinaryRegions([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp = []BinaryRegion{{Start: 32, End: 34, Kind: "inline image"}}
+	if !reflect.DeepEqual(regions, exp) {
+		t.Errorf("expected %v, got %v", exp, regions)
+	}
+	if string(content[32:34]) != "\x10\x20" {
+		t.Errorf("unexpected image data %q", content[32:34])
+	}
+
+	if _, err = FindBinaryRegions([]byte("<< >> stream\nabc")); err == nil {
+		t.Error("expected error for missing endstream")
+	}
+}
@@ -0,0 +1,326 @@
+package tokenizer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ExtractText tokenizes `data` and returns the decoded text of
+// all the String and StringHex tokens (see `Token.DecodedString`).
+// As `Tokenize`, it stops at binary streams and inline data.
+func ExtractText(data []byte) ([]string, error) {
+	tk := NewTokenizer(data)
+	var out []string
+	for {
+		t, err := tk.NextToken()
+		if err != nil {
+			return nil, err
+		}
+		switch t.Kind {
+		case EOF:
+			return out, nil
+		case String, StringHex:
+			s, err := t.DecodedString()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, s)
+		}
+	}
+}
+
+// DecodedString interprets a String or StringHex token as a text string,
+// decoding UTF-16BE (when starting with the 0xFE 0xFF BOM),
+// UTF-8 (when starting with the 0xEF 0xBB 0xBF BOM) or PDFDocEncoding.
+func (t Token) DecodedString() (string, error) {
+	if t.Kind != String && t.Kind != StringHex {
+		return "", fmt.Errorf("expected String, got %s", t.Kind)
+	}
+	return decodeTextString(t.Value)
+}
+
+// decodeTextString decodes a PDF text string (see 7.9.2.2 - Text String Type),
+// which is either UTF-16BE (with a BOM), UTF-8 (with a BOM) or PDFDocEncoding.
+func decodeTextString(b []byte) (string, error) {
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		b = b[2:]
+		if len(b)%2 != 0 {
+			return "", errors.New("invalid UTF-16 string: odd length")
+		}
+		u16 := make([]uint16, len(b)/2)
+		for i := range u16 {
+			u16[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+		}
+		return string(utf16.Decode(u16)), nil
+	}
+	if len(b) >= 3 && b[0] == 0xEF && b[1] == 0xBB && b[2] == 0xBF {
+		b = b[3:]
+		if !utf8.Valid(b) {
+			return "", errors.New("invalid UTF-8 string")
+		}
+		return string(b), nil
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = pdfDocEncoding[c]
+	}
+	return string(runes), nil
+}
+
+// ParseDate interprets a String (or StringHex) token as a PDF date,
+// with format D:YYYYMMDDHHmmSSOHH'mm' (see 7.9.4 - Dates).
+// The "D:" prefix and all the fields after the year are optional;
+// when the timezone is missing, UTC is assumed.
+func (t Token) ParseDate() (time.Time, error) {
+	if t.Kind != String && t.Kind != StringHex {
+		return time.Time{}, fmt.Errorf("expected String for a date, got %s", t.Kind)
+	}
+	s := string(t.Value)
+	if len(s) >= 2 && s[:2] == "D:" {
+		s = s[2:]
+	}
+
+	// reads n digits, if present
+	readField := func(n int, def int, min, max int) (int, error) {
+		if len(s) == 0 || s[0] < '0' || s[0] > '9' {
+			return def, nil
+		}
+		if len(s) < n {
+			return 0, fmt.Errorf("invalid date field %s", s)
+		}
+		v, err := strconv.Atoi(s[:n])
+		if err != nil {
+			return 0, fmt.Errorf("invalid date field %s", s[:n])
+		}
+		if v < min || v > max {
+			return 0, fmt.Errorf("date field %d out of range", v)
+		}
+		s = s[n:]
+		return v, nil
+	}
+
+	if len(s) < 4 {
+		return time.Time{}, errors.New("invalid date: missing year")
+	}
+	var fields [6]int
+	for i, f := range [6]struct{ n, def, min, max int }{
+		{4, 0, 0, 9999}, // year, required by the check above
+		{2, 1, 1, 12},   // month
+		{2, 1, 1, 31},   // day
+		{2, 0, 0, 23},   // hour
+		{2, 0, 0, 59},   // minute
+		{2, 0, 0, 59},   // second
+	} {
+		v, err := readField(f.n, f.def, f.min, f.max)
+		if err != nil {
+			return time.Time{}, err
+		}
+		fields[i] = v
+	}
+
+	loc := time.UTC
+	if len(s) != 0 {
+		sign := s[0]
+		s = s[1:]
+		switch sign {
+		case 'Z':
+			// some writers add a (meaningless) offset after Z
+		case '+', '-':
+			hours, err := readField(2, 0, 0, 23)
+			if err != nil {
+				return time.Time{}, err
+			}
+			if len(s) != 0 && s[0] == '\'' {
+				s = s[1:]
+			}
+			minutes, err := readField(2, 0, 0, 59)
+			if err != nil {
+				return time.Time{}, err
+			}
+			offset := hours*3600 + minutes*60
+			if sign == '-' {
+				offset = -offset
+			}
+			loc = time.FixedZone("", offset)
+		default:
+			return time.Time{}, fmt.Errorf("invalid date timezone %s", string(sign))
+		}
+	}
+	return time.Date(fields[0], time.Month(fields[1]), fields[2], fields[3], fields[4], fields[5], 0, loc), nil
+}
+
+// pdfDocEncoding maps the PDFDocEncoding bytes to runes (see Annex D.2).
+// Undefined codes are mapped to the unicode replacement character.
+var pdfDocEncoding = func() (out [256]rune) {
+	// PDFDocEncoding mostly agrees with Latin-1
+	for i := range out {
+		out[i] = rune(i)
+	}
+	for i, r := range [...]rune{0x02D8, 0x02C7, 0x02C6, 0x02D9, 0x02DD, 0x02DB, 0x02DA, 0x02DC} {
+		out[0x18+i] = r
+	}
+	for i, r := range [...]rune{
+		0x2022, 0x2020, 0x2021, 0x2026, 0x2014, 0x2013, 0x0192, 0x2044,
+		0x2039, 0x203A, 0x2212, 0x2030, 0x201E, 0x201C, 0x201D, 0x2018,
+		0x2019, 0x201A, 0x2122, 0xFB01, 0xFB02, 0x0141, 0x0152, 0x0160,
+		0x0178, 0x017D, 0x0131, 0x0142, 0x0153, 0x0161, 0x017E, utf8.RuneError,
+		0x20AC,
+	} {
+		out[0x80+i] = r
+	}
+	out[0x7F] = utf8.RuneError
+	out[0xAD] = utf8.RuneError
+	return out
+}()
+
+// AppendHexDecoded decodes the body of an hexadecimal string
+// (the content between '<' and '>'), appending the bytes to `dst`.
+// White spaces are ignored and a final odd digit is completed
+// with 0, as specified in 7.3.4.3 - Hexadecimal Strings.
+func AppendHexDecoded(dst []byte, raw []byte) ([]byte, error) {
+	var (
+		hi      byte
+		hasHigh bool
+	)
+	for _, c := range raw {
+		if IsAsciiWhitespace(c) {
+			continue
+		}
+		v, ok := IsHexChar(c)
+		if !ok {
+			return dst, fmt.Errorf("invalid hex char %d", c)
+		}
+		if hasHigh {
+			dst = append(dst, hi<<4|v)
+		} else {
+			hi = v
+		}
+		hasHigh = !hasHigh
+	}
+	if hasHigh {
+		dst = append(dst, hi<<4)
+	}
+	return dst, nil
+}
+
+// DecodeName returns the value of a Name token, with
+// the #XX escape sequences resolved (see 7.3.5 - Name Objects).
+// `Value` stores the raw name.
+func (t Token) DecodeName() ([]byte, error) {
+	if t.Kind != Name {
+		return nil, fmt.Errorf("expected Name, got %s", t.Kind)
+	}
+	out := make([]byte, 0, len(t.Value))
+	for i := 0; i < len(t.Value); i++ {
+		c := t.Value[i]
+		if c != '#' {
+			out = append(out, c)
+			continue
+		}
+		if i+2 >= len(t.Value) {
+			return nil, fmt.Errorf("invalid escape in name %s", t.Value)
+		}
+		h1, ok1 := IsHexChar(t.Value[i+1])
+		h2, ok2 := IsHexChar(t.Value[i+2])
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("invalid escape in name %s", t.Value)
+		}
+		out = append(out, h1<<4|h2)
+		i += 2
+	}
+	return out, nil
+}
+
+// EncodeLiteralString returns the PDF literal string (including the enclosing parenthesis)
+// representing `data`, escaping parenthesis, backslashes and
+// the control characters with a dedicated escape sequence.
+// It is the inverse of the tokenizer decoding of String tokens.
+func EncodeLiteralString(data []byte) []byte {
+	out := make([]byte, 0, len(data)+2)
+	out = append(out, '(')
+	for _, c := range data {
+		switch c {
+		case '(', ')', '\\':
+			out = append(out, '\\', c)
+		case '\n':
+			out = append(out, '\\', 'n')
+		case '\r': // required, since a raw CR would be read as LF
+			out = append(out, '\\', 'r')
+		case '\t':
+			out = append(out, '\\', 't')
+		case '\b':
+			out = append(out, '\\', 'b')
+		case '\f':
+			out = append(out, '\\', 'f')
+		default:
+			out = append(out, c)
+		}
+	}
+	out = append(out, ')')
+	return out
+}
+
+// ValidateStringEscapes checks that the escape sequences of
+// a String token are well-formed, according to 7.3.4.2 - Literal Strings,
+// that is it rejects unknown escapes (which are ignored by the tokenizer),
+// octal values overflowing a byte and trailing backslashes.
+// Since escape sequences are resolved by default, the token
+// must have been read with the `RawStrings` option.
+func (t Token) ValidateStringEscapes() error {
+	if t.Kind != String {
+		return fmt.Errorf("expected String, got %s", t.Kind)
+	}
+	raw := t.Value
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' {
+			continue
+		}
+		i++
+		if i >= len(raw) {
+			return errors.New("invalid trailing backslash in string")
+		}
+		switch c := raw[i]; c {
+		case 'n', 'r', 't', 'b', 'f', '(', ')', '\\', '\r', '\n':
+		default:
+			if c < '0' || c > '7' {
+				return fmt.Errorf("invalid escape sequence \\%s at %d", string(rune(c)), i-1)
+			}
+			// up to 3 octal digits
+			start, octal := i-1, int(c-'0')
+			for j := 0; j < 2 && i+1 < len(raw) && '0' <= raw[i+1] && raw[i+1] <= '7'; j++ {
+				i++
+				octal = octal<<3 + int(raw[i]-'0')
+			}
+			if octal > 0xff {
+				return fmt.Errorf("invalid octal escape sequence %s at %d", raw[start:i+1], start)
+			}
+		}
+	}
+	return nil
+}
+
+// DecodedValue returns the value of a String token read with
+// the `RawStrings` option, after processing the escape sequences
+// and EOL, as done by default by the tokenizer.
+func (t Token) DecodedValue() ([]byte, error) {
+	if t.Kind != String {
+		return nil, fmt.Errorf("expected String, got %s", t.Kind)
+	}
+	src := make([]byte, 0, len(t.Value)+2)
+	src = append(src, '(')
+	src = append(src, t.Value...)
+	src = append(src, ')')
+	tk := NewTokenizer(src)
+	out, err := tk.NextToken()
+	if err != nil {
+		return nil, err
+	}
+	if out.Kind != String || tk.CurrentPosition() != len(src) {
+		return nil, fmt.Errorf("invalid raw string %q", t.Value)
+	}
+	return out.Value, nil
+}
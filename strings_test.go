@@ -0,0 +1,276 @@
+package tokenizer
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		exp   time.Time
+	}{
+		{"D:20240101120000+00'00'", time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{"D:19981223195200-08'00'", time.Date(1998, 12, 23, 19, 52, 0, 0, time.FixedZone("", -8*3600))},
+		{"D:20240315083010+05'30", time.Date(2024, 3, 15, 8, 30, 10, 0, time.FixedZone("", 5*3600+30*60))},
+		{"D:20240315083010Z", time.Date(2024, 3, 15, 8, 30, 10, 0, time.UTC)},
+		{"D:20240315083010Z00'00'", time.Date(2024, 3, 15, 8, 30, 10, 0, time.UTC)},
+		{"D:2024", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"D:202403", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"D:2024031508", time.Date(2024, 3, 15, 8, 0, 0, 0, time.UTC)},
+		{"20240315", time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+	} {
+		tks, err := Tokenize([]byte("(" + test.input + ")"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := tks[0].ParseDate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(test.exp) {
+			t.Errorf("expected %s, got %s", test.exp, got)
+		}
+	}
+
+	for _, input := range []string{"D:", "D:20", "D:202413", "D:2024011", "D:20240101X"} {
+		tk := Token{Kind: String, Value: []byte(input)}
+		if _, err := tk.ParseDate(); err == nil {
+			t.Errorf("expected error for %s", input)
+		}
+	}
+	if _, err := (Token{Kind: Name, Value: []byte("D:2024")}).ParseDate(); err == nil {
+		t.Error("expected error for Name token")
+	}
+}
+
+func TestExtractText(t *testing.T) {
+	input := []byte(`BT (Hello) Tj <FEFF00E9007400E9> Tj [(a\223b) 5 <41 42>] TJ (\376\377\000O\000K) Tj ET
+	stream (not text)`)
+	got, err := ExtractText(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{"Hello", "été", "aﬁb", "AB", "OK"}
+	if !reflect.DeepEqual(got, exp) {
+		t.Fatalf("expected %q, got %q", exp, got)
+	}
+
+	if _, err = ExtractText([]byte("<FEFF00>")); err == nil {
+		t.Fatal("expected error for odd UTF-16 string")
+	}
+	if _, err = ExtractText([]byte("(abc")); err == nil {
+		t.Fatal("expected error for invalid input")
+	}
+}
+
+func TestAppendHexDecoded(t *testing.T) {
+	for _, test := range []struct {
+		raw string
+		exp []byte
+	}{
+		{"", nil},
+		{"4142", []byte("AB")},
+		{"41 4 2\n43", []byte("ABC")},
+		{"ABC", []byte{0xAB, 0xC0}},
+		{"fe ff", []byte{0xFE, 0xFF}},
+	} {
+		got, err := AppendHexDecoded([]byte("x"), []byte(test.raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := append([]byte("x"), test.exp...); !bytes.Equal(got, exp) {
+			t.Errorf("expected %v, got %v", exp, got)
+		}
+		// consistent with the tokenizer
+		tks, err := Tokenize([]byte("<" + test.raw + ">"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(tks[0].Value, test.exp) {
+			t.Errorf("expected %v, got %v", tks[0].Value, test.exp)
+		}
+	}
+	if _, err := AppendHexDecoded(nil, []byte("4G")); err == nil {
+		t.Error("expected error for invalid hex char")
+	}
+}
+
+var hexStrings = func() (out [][]byte) {
+	for i := 0; i < 1000; i++ {
+		out = append(out, []byte(fmt.Sprintf("%08X %08X 7465787420737472696E67", i, 3*i)))
+	}
+	return out
+}()
+
+func BenchmarkHexDecoded(b *testing.B) {
+	b.Run("allocating", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, raw := range hexStrings {
+				AppendHexDecoded(nil, raw)
+			}
+		}
+	})
+	b.Run("reusing buffer", func(b *testing.B) {
+		b.ReportAllocs()
+		var buf []byte
+		for i := 0; i < b.N; i++ {
+			for _, raw := range hexStrings {
+				buf, _ = AppendHexDecoded(buf[:0], raw)
+			}
+		}
+	})
+}
+
+func TestDecodeName(t *testing.T) {
+	tks, err := Tokenize([]byte("/Name#20With#23Hash /Type /A#2fB#2F /"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, exp := range []string{"Name With#Hash", "Type", "A/B/", ""} {
+		got, err := tks[i].DecodeName()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != exp {
+			t.Errorf("expected %s, got %s", exp, got)
+		}
+	}
+	if string(tks[0].Value) != "Name#20With#23Hash" {
+		t.Errorf("expected raw value, got %s", tks[0].Value)
+	}
+
+	for _, tk := range []Token{
+		{Kind: Name, Value: []byte("A#2")},
+		{Kind: Name, Value: []byte("A#GG")},
+		{Kind: String, Value: []byte("A")},
+	} {
+		if _, err := tk.DecodeName(); err == nil {
+			t.Errorf("expected error for %v", tk)
+		}
+	}
+}
+
+func TestDecodedString(t *testing.T) {
+	tks, err := Tokenize([]byte(`(Hello) <FEFF004800690020263A> (\376\377\000H\000i) (caf\351 \200) <EFBBBF636166C3A9> /Name`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, exp := range []string{"Hello", "Hi ☺", "Hi", "café •", "café"} {
+		got, err := tks[i].DecodedString()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != exp {
+			t.Errorf("expected %q, got %q", exp, got)
+		}
+	}
+	if _, err = tks[5].DecodedString(); err == nil {
+		t.Error("expected error for Name token")
+	}
+}
+
+func TestEncodeLiteralString(t *testing.T) {
+	for _, test := range []struct {
+		input, exp string
+	}{
+		{"", "()"},
+		{"Hello", "(Hello)"},
+		{"a(b)c", `(a\(b\)c)`},
+		{"a)b(", `(a\)b\()`},
+		{`C:\dir`, `(C:\\dir)`},
+		{"l1\nl2\r\tx\b\f", `(l1\nl2\r\tx\b\f)`},
+	} {
+		if got := EncodeLiteralString([]byte(test.input)); string(got) != test.exp {
+			t.Errorf("expected %s, got %s", test.exp, got)
+		}
+	}
+}
+
+func FuzzEncodeLiteralString(f *testing.F) {
+	for _, seed := range []string{"", "Hello", "a(b)c", ")(", `\\`, "\r\n\r", "\x00\xff\\053"} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		tks, err := Tokenize(EncodeLiteralString(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tks) != 1 || tks[0].Kind != String {
+			t.Fatalf("expected one String, got %v", tks)
+		}
+		if !bytes.Equal(tks[0].Value, data) {
+			t.Fatalf("expected %v, got %v", data, tks[0].Value)
+		}
+	})
+}
+
+func TestValidateStringEscapes(t *testing.T) {
+	input := []byte(`(a\(b\) \n\r\t\b\f\\ \053\53\0539 \7 (nested) \` + "\r\n" + `) (bad \400) (bad \9) (bad \q)`)
+
+	tk := NewTokenizer(input)
+	tk.RawStrings = true
+	tk.SetPosition(0)
+	tks, err := tk.readAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tks) != 4 {
+		t.Fatalf("expected 4 tokens, got %v", tks)
+	}
+	if exp := `a\(b\) \n\r\t\b\f\\ \053\53\0539 \7 (nested) \` + "\r\n"; string(tks[0].Value) != exp {
+		t.Errorf("expected raw value %q, got %q", exp, tks[0].Value)
+	}
+	if err = tks[0].ValidateStringEscapes(); err != nil {
+		t.Error(err)
+	}
+	for _, tk := range tks[1:] {
+		if err = tk.ValidateStringEscapes(); err == nil {
+			t.Errorf("expected error for %s", tk.Value)
+		}
+	}
+	if err = (Token{Kind: String, Value: []byte(`abc\`)}).ValidateStringEscapes(); err == nil {
+		t.Error("expected error for trailing backslash")
+	}
+}
+
+func TestDecodedValue(t *testing.T) {
+	input := []byte(`(a\0053b) (x\(y\)` + "\r\nz" + `)`)
+	raw, err := NewTokenizer(input, WithRawStrings()).readAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Tokenize(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := `a\0053b`; string(raw[0].Value) != exp {
+		t.Errorf("expected raw value %q, got %q", exp, raw[0].Value)
+	}
+	for i := range raw {
+		got, err := raw[i].DecodedValue()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, decoded[i].Value) {
+			t.Errorf("expected %q, got %q", decoded[i].Value, got)
+		}
+	}
+	if exp := "a\x053b"; string(decoded[0].Value) != exp {
+		t.Errorf("expected %q, got %q", exp, decoded[0].Value)
+	}
+
+	for _, tk := range []Token{
+		{Kind: Name, Value: []byte("a")},
+		{Kind: String, Value: []byte("a) (b")},
+		{Kind: String, Value: []byte("a(b")},
+	} {
+		if _, err := tk.DecodedValue(); err == nil {
+			t.Errorf("expected error for %v", tk)
+		}
+	}
+}
@@ -6,11 +6,14 @@ package tokenizer
 // Code ported from the Java PDFTK library - BK 2020
 
 import (
-	"encoding/hex"
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"strconv"
+	"strings"
 )
 
 type Fl = float64
@@ -35,6 +38,8 @@ const (
 	StartProc  // only valid in PostScript files
 	EndProc    // idem
 	CharString // PS only: binary stream, introduce by and integer and a RD or -| command
+
+	Comment // only emitted when using the EmitComments option
 )
 
 func (k Kind) String() string {
@@ -67,11 +72,82 @@ func (k Kind) String() string {
 		return "EndProc"
 	case CharString:
 		return "CharString"
+	case Comment:
+		return "Comment"
 	default:
 		return "<invalid token>"
 	}
 }
 
+// Category groups the token kinds into broad classes.
+type Category uint8
+
+const (
+	_         Category = iota
+	Scalar             // numbers, strings and names
+	Open               // start of array, dictionary or proc
+	Close              // end of array, dictionary or proc
+	Keyword            // Other (commands and keywords)
+	Binary             // CharString
+	End                // EOF
+	Ignorable          // Comment
+)
+
+func (c Category) String() string {
+	switch c {
+	case Scalar:
+		return "Scalar"
+	case Open:
+		return "Open"
+	case Close:
+		return "Close"
+	case Keyword:
+		return "Keyword"
+	case Binary:
+		return "Binary"
+	case End:
+		return "End"
+	case Ignorable:
+		return "Ignorable"
+	default:
+		return "<invalid category>"
+	}
+}
+
+// Category returns the class of the token kind,
+// or 0 for an invalid kind.
+func (t Token) Category() Category { return t.Kind.category() }
+
+// IsOpen returns true for StartArray, StartDic and StartProc.
+func (k Kind) IsOpen() bool { return k.category() == Open }
+
+// IsClose returns true for EndArray, EndDic and EndProc.
+func (k Kind) IsClose() bool { return k.category() == Close }
+
+// IsValue returns true for numbers, strings and names.
+func (k Kind) IsValue() bool { return k.category() == Scalar }
+
+func (k Kind) category() Category {
+	switch k {
+	case Float, Integer, String, StringHex, Name:
+		return Scalar
+	case StartArray, StartDic, StartProc:
+		return Open
+	case EndArray, EndDic, EndProc:
+		return Close
+	case Other:
+		return Keyword
+	case CharString:
+		return Binary
+	case EOF:
+		return End
+	case Comment:
+		return Ignorable
+	default:
+		return 0
+	}
+}
+
 func isEOL(ch byte) bool {
 	return ch == '\n' || ch == '\r'
 }
@@ -106,17 +182,149 @@ func isDigit(ch byte) bool {
 // which is left to parsing packages.
 type Token struct {
 	// Additional value found in the data
-	// Note that it is a copy of the source bytes.
+	// Note that it is a copy of the source bytes, unless
+	// the ZeroCopy or ReuseValues options are set.
 	Value []byte
 	Kind  Kind
+
+	// Radix is the base of PostScript radix numbers (like 16 in 16#FFFE),
+	// or 0 for decimal numbers.
+	// For such numbers, `Value` is converted to decimal and the original
+	// digits are returned by `RadixDigits` (like FFFE).
+	Radix uint8
+
+	// rarely used information, kept out of the struct
+	// so that the common tokens stay small
+	meta *tokenMeta
+}
+
+type tokenMeta struct {
+	radixDigits []byte
+	command     []byte
+}
+
+// NewCharString returns a CharString token, introduced by
+// `command` (like RD or -|).
+func NewCharString(command, data []byte) Token {
+	return Token{Kind: CharString, Value: data, meta: &tokenMeta{command: command}}
+}
+
+// Command returns the keyword introducing CharString tokens
+// (RD or -|), or nil.
+func (t Token) Command() []byte {
+	if t.meta == nil {
+		return nil
+	}
+	return t.meta.command
+}
+
+// RadixDigits returns the original digits of radix numbers
+// (like FFFE for 16#FFFE), or nil.
+func (t Token) RadixDigits() []byte {
+	if t.meta == nil {
+		return nil
+	}
+	return t.meta.radixDigits
+}
+
+// Exponential returns true for Float tokens written in
+// exponential notation (like 6.02E23).
+func (t Token) Exponential() bool {
+	return t.Kind == Float && bytes.IndexAny(t.Value, "eE") != -1
+}
+
+// Equal returns true if the tokens have the same kind and value.
+// The other fields (radix, charstring command) are ignored.
+func (t Token) Equal(o Token) bool {
+	return t.Kind == o.Kind && bytes.Equal(t.Value, o.Value)
+}
+
+// String returns a readable form of the token, like Name("Type"),
+// meant for debugging.
+// The value of CharString tokens is replaced by its length.
+func (t Token) String() string {
+	switch t.Kind {
+	case CharString:
+		return fmt.Sprintf("CharString(%d bytes)", len(t.Value))
+	case StartArray, EndArray, StartDic, EndDic, StartProc, EndProc, EOF:
+		return t.Kind.String()
+	default:
+		return fmt.Sprintf("%s(%q)", t.Kind, t.Value)
+	}
 }
 
 // Int returns the integer value of the token,
 // also accepting float values and rouding them.
+// Integer tokens are parsed exactly (like 00000 or 65535 generation numbers),
+// without going through a float.
+// Values overflowing int are reported with an error wrapping
+// strconv.ErrRange.
 func (t Token) Int() (int, error) {
-	// also accepts floats and round
+	i, err := t.Int64()
+	if err != nil {
+		return 0, err
+	}
+	if int64(int(i)) != i {
+		return 0, fmt.Errorf("integer %s: %w", t.Value, strconv.ErrRange)
+	}
+	return int(i), nil
+}
+
+// Int64 returns the integer value of the token, preserving
+// the 64 bits of integers (which are not rounded through a float).
+// Float values are also accepted and rounded.
+func (t Token) Int64() (int64, error) {
+	if t.Kind != Float {
+		i, err := strconv.ParseInt(string(t.Value), 10, 64)
+		if err == nil || errors.Is(err, strconv.ErrRange) {
+			return i, err
+		}
+		// an integer stored as float : use the float path
+	}
 	f, err := t.Float()
-	return int(f), err
+	if err != nil {
+		return 0, err
+	}
+	// float64(math.MaxInt64) is 2^63
+	if !(-(1<<63) <= f && f < 1<<63) {
+		return 0, fmt.Errorf("integer %s: %w", t.Value, strconv.ErrRange)
+	}
+	return int64(f), nil
+}
+
+// Uint64 is the same as Int64, but rejects negative values.
+// It is useful to read byte offsets.
+func (t Token) Uint64() (uint64, error) {
+	if len(t.Value) != 0 && t.Value[0] == '-' {
+		return 0, fmt.Errorf("invalid negative value %s", t.Value)
+	}
+	if t.Kind != Float {
+		i, err := strconv.ParseUint(string(t.Value), 10, 64)
+		if err == nil || errors.Is(err, strconv.ErrRange) {
+			return i, err
+		}
+	}
+	f, err := t.Float()
+	if err != nil {
+		return 0, err
+	}
+	if !(0 <= f && f < 1<<64) {
+		return 0, fmt.Errorf("integer %s: %w", t.Value, strconv.ErrRange)
+	}
+	return uint64(f), nil
+}
+
+// BigInt returns the exact value of an Integer token,
+// without any size limitation.
+func (t Token) BigInt() (*big.Int, error) {
+	if t.Kind != Integer {
+		return nil, fmt.Errorf("expected Integer, got %s", t.Kind)
+	}
+	out, ok := new(big.Int).SetString(string(t.Value), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid integer %s", t.Value)
+	}
+	return out, nil
 }
 
 // Float returns the float value of the token.
@@ -140,17 +348,55 @@ func (t Token) IsOther(value string) bool {
 	return t.Kind == Other && string(t.Value) == value
 }
 
+// IsBool returns the value of a `true` or `false` keyword,
+// and false as second value for other tokens.
+func (t Token) IsBool() (value, ok bool) {
+	if t.IsOther("true") {
+		return true, true
+	}
+	return false, t.IsOther("false")
+}
+
+// IsNull returns true for the `null` keyword.
+func (t Token) IsNull() bool { return t.IsOther("null") }
+
+// IsRef returns true for the `R` keyword, ending indirect references.
+func (t Token) IsRef() bool { return t.IsOther("R") }
+
+// TokenizerError is returned when the input is invalid,
+// and stores the position where the error occurred.
+type TokenizerError struct {
+	Pos int    // offset in the input
+	Msg string // description of the error
+}
+
+func (err *TokenizerError) Error() string {
+	return fmt.Sprintf("%s at offset %d", err.Msg, err.Pos)
+}
+
+func errorAt(pos int, format string, args ...interface{}) error {
+	return &TokenizerError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
 // Tokenize consume all the input, splitting it
 // into tokens.
+// Options (like `WithMaxTokens`) may be given to configure the tokenizer.
 // When performance matters, you should use
 // the iteration method `NextToken` of the Tokenizer type.
-func Tokenize(data []byte) ([]Token, error) {
-	tk := NewTokenizer(data)
-	return tk.readAll()
+func Tokenize(data []byte, opts ...Option) ([]Token, error) {
+	return TokenizeAppend(nil, data, opts...)
+}
+
+// TokenizeAppend is the same as `Tokenize`, but appends the tokens
+// to dst[:0], so that the slice may be reused between calls.
+func TokenizeAppend(dst []Token, data []byte, opts ...Option) ([]Token, error) {
+	tk := NewTokenizer(data, opts...)
+	return tk.appendAll(dst[:0])
 }
 
-func (tk *Tokenizer) readAll() ([]Token, error) {
-	var out []Token
+func (tk *Tokenizer) readAll() ([]Token, error) { return tk.appendAll(nil) }
+
+func (tk *Tokenizer) appendAll(out []Token) ([]Token, error) {
 	t, err := tk.NextToken()
 	for ; t.Kind != EOF && err == nil; t, err = tk.NextToken() {
 		out = append(out, t)
@@ -161,12 +407,19 @@ func (tk *Tokenizer) readAll() ([]Token, error) {
 // Tokenizer is a PS/PDF tokenizer.
 //
 // It handles PS features like Procs and CharStrings:
-// strict parsers should check for such tokens and return an error if needed.
+// strict parsers should check for such tokens and return an error if needed,
+// or use the `Strict` option.
 //
-// Comments are ignored.
+// Comments are ignored, unless the `EmitComments` option is set.
 //
 // The tokenizer can't handle streams and inline image data on it's own.
 //
+// The input is never modified, and token values are copies of it, so that
+// a memory-mapped file may be used as input: tokens may outlive the mapping.
+// However, the slices returned by `Bytes` and `SkipBytes` point into the input,
+// as do the token values when the `ZeroCopy` option is set: such tokens
+// must then not be used after the mapping is released.
+//
 // Regarding exponential numbers: 7.3.3 Numeric Objects:
 // A conforming writer shall not use the PostScript syntax for numbers
 // with non-decimal radices (such as 16#FFFE) or in exponential format
@@ -175,11 +428,23 @@ func (tk *Tokenizer) readAll() ([]Token, error) {
 // we support it in the tokenizer (no confusion with other types, so
 // no compromise).
 type Tokenizer struct {
+	// Options may be set with `NewTokenizer` or `NewTokenizerFromReader`,
+	// or directly on the Tokenizer: since the tokenizer reads two tokens ahead,
+	// changes then only apply to the next tokenizations, so that
+	// `Reset` or `SetPosition` should be called after setting them.
+	config
+
 	numberSb []byte // buffer to avoid allocations
 
 	data []byte
 	src  io.Reader // if not nil, 'data' will be read from it
 
+	// in random access mode, src reads from ra, and
+	// data is reloaded when jumping out of it
+	ra     io.ReaderAt
+	raSize int64
+	base   int // offset of data[0] in the input
+
 	// since indirect reference require
 	// to read two more tokens
 	// we store the two next token
@@ -190,17 +455,43 @@ type Tokenizer struct {
 	aaError error // +2
 	aaToken Token // +2
 
+	lastError error // -1
+	lastToken Token // -1
+
 	pos int // main position (end of the aaToken)
 
 	currentPos int // end of the current token
 	nextPos    int // end of the +1 token
 
+	nbTokens int // number of tokens returned by NextToken
+	depth    int // nesting level of the consumed tokens
+
+	unread unreadState // state before the last NextToken
+
+	// derived from the input, lazily computed
+	// and cleared on Reset
+	lines lineIndex
+
+	trivia []Trivia // with CaptureTrivia
+
+	// with ReuseValues, one buffer per token in flight:
+	// the previous one (for UnreadToken), the current one
+	// and the two lookahead tokens
+	values      [4][]byte
+	valuesIndex int
+
+	// set during NextTokenCtx
+	ctx    context.Context
+	ctxErr error
 }
 
 // NewTokenizer returns a tokenizer working on the
 // given input.
-func NewTokenizer(data []byte) *Tokenizer {
+func NewTokenizer(data []byte, opts ...Option) *Tokenizer {
 	tk := Tokenizer{data: data}
+	for _, opt := range opts {
+		opt(&tk.config)
+	}
 	tk.SetPosition(0)
 	return &tk
 }
@@ -210,6 +501,8 @@ func NewTokenizer(data []byte) *Tokenizer {
 func (tk *Tokenizer) Reset(data []byte) {
 	tk.data = data
 	tk.src = nil
+	tk.ra, tk.base = nil, 0
+	tk.resetCaches()
 	tk.SetPosition(0)
 }
 
@@ -220,8 +513,11 @@ func (tk *Tokenizer) Reset(data []byte) {
 // the internal buffer is simply not grown.
 // See `SetPosition`, `SkipBytes` and `Bytes` for more information
 // of the behavior in this mode.
-func NewTokenizerFromReader(src io.Reader) *Tokenizer {
+func NewTokenizerFromReader(src io.Reader, opts ...Option) *Tokenizer {
 	tk := &Tokenizer{src: src}
+	for _, opt := range opts {
+		opt(&tk.config)
+	}
 	tk.SetPosition(0)
 	return tk
 }
@@ -231,10 +527,114 @@ func NewTokenizerFromReader(src io.Reader) *Tokenizer {
 func (tk *Tokenizer) ResetFromReader(src io.Reader) {
 	tk.data = tk.data[:0]
 	tk.src = src
+	tk.ra, tk.base = nil, 0
+	tk.resetCaches()
 	tk.SetPosition(0)
 }
 
+// NewTokenizerFromReaderAt supports tokenizing a random access input of
+// the given size (like an *os.File), without reading it entirely.
+// The data is read on demand, as in `NewTokenizerFromReader`, and
+// `SetPosition` may be used to jump to arbitrary offsets: the internal
+// buffer is then discarded and reading starts again from the new position.
+func NewTokenizerFromReaderAt(r io.ReaderAt, size int64, opts ...Option) *Tokenizer {
+	tk := &Tokenizer{ra: r, raSize: size}
+	for _, opt := range opts {
+		opt(&tk.config)
+	}
+	tk.reload(0)
+	tk.setPosition(0)
+	return tk
+}
+
+// reload discards the internal buffer so that
+// reading starts at `offset` in the random access input
+func (tk *Tokenizer) reload(offset int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if int64(offset) > tk.raSize {
+		offset = int(tk.raSize)
+	}
+	tk.data = tk.data[:0]
+	tk.base = offset
+	tk.src = io.NewSectionReader(tk.ra, int64(offset), tk.raSize-int64(offset))
+	tk.lines.reset()
+}
+
+// SubTokenizer returns a tokenizer working on the region [start, end) of the input,
+// sharing the same underlying data and options.
+// Positions (like `CurrentPosition`) are still offsets in the whole input.
+// In reader mode, the input is buffered up to `end` if needed.
+func (tk *Tokenizer) SubTokenizer(start, end int) *Tokenizer {
+	start, end = start-tk.base, end-tk.base
+	if tk.src != nil && end > len(tk.data) {
+		tk.grow(end - len(tk.data))
+	}
+	if end > len(tk.data) {
+		end = len(tk.data)
+	}
+	if start > end {
+		start = end
+	}
+	if start < 0 {
+		start = 0
+	}
+	sub := &Tokenizer{
+		config: tk.config,
+		data:   tk.data[:end:end], // protect the end of the data
+		base:   tk.base,
+	}
+	sub.setPosition(start)
+	return sub
+}
+
+// Clone returns a copy of the tokenizer, at the same position,
+// so that both may be advanced independently (for instance to
+// parse ahead speculatively).
+// The input is shared, except in reader mode, where the buffered data is copied :
+// for an io.Reader source, the clone then only sees the data already buffered,
+// whereas an io.ReaderAt source is read again.
+func (tk *Tokenizer) Clone() *Tokenizer {
+	out := *tk
+	out.numberSb = nil
+	out.lines = lineIndex{}
+	out.trivia = tk.trivia[:len(tk.trivia):len(tk.trivia)]
+	if tk.src != nil {
+		out.data = copyBytes(tk.data)
+		out.src = nil
+		if tk.ra != nil {
+			offset := int64(tk.base + len(tk.data))
+			out.src = io.NewSectionReader(tk.ra, offset, tk.raSize-offset)
+		}
+	}
+	if tk.ReuseValues { // the values in flight belong to tk
+		out.values = [len(tk.values)][]byte{}
+		for _, t := range []*Token{&out.aToken, &out.aaToken, &out.lastToken, &out.unread.lastToken} {
+			t.Value = copyBytes(t.Value)
+			if t.meta != nil && t.meta.command != nil {
+				t.meta = &tokenMeta{command: copyBytes(t.meta.command)}
+			}
+		}
+	}
+	return &out
+}
+
+// resetCaches clears the state derived from the
+// previous input, but keeps the configuration
+func (tk *Tokenizer) resetCaches() {
+	tk.nbTokens = 0
+	tk.lines.reset()
+	tk.trivia = tk.trivia[:0]
+}
+
 func (tk *Tokenizer) grow(size int) {
+	if tk.ctx != nil {
+		if err := tk.ctx.Err(); err != nil {
+			tk.ctxErr = err
+			return
+		}
+	}
 	currentLen := len(tk.data)
 	if cap(tk.data) < currentLen+size {
 		tk.data = append(tk.data, make([]byte, size)...)
@@ -243,13 +643,43 @@ func (tk *Tokenizer) grow(size int) {
 	tk.data = tk.data[:currentLen+n] // actual content read
 }
 
+// growTo reads from the source until the buffer holds `end` bytes,
+// and returns false if the input is too short.
+// The buffer is grown by chunks, to avoid allocating for absurd lengths.
+func (tk *Tokenizer) growTo(end int) bool {
+	for tk.src != nil && end > len(tk.data) {
+		L := len(tk.data)
+		tk.grow(tk.bufferSize())
+		if len(tk.data) == L { // no more data
+			break
+		}
+	}
+	return end <= len(tk.data)
+}
+
 // SetPosition set the position of the tokenizer in the input data.
 //
 // Most of the time, `NextToken` should be preferred, but this method may be used
 // for example to go back to a saved position.
 //
 // When using an io.Reader as source, no additional buffering is performed.
+// When using an io.ReaderAt, the input is read again from `pos` if needed.
+// Positions discarded by `CompactBuffer` are not accessible anymore.
 func (tk *Tokenizer) SetPosition(pos int) {
+	pos -= tk.base
+	if tk.ra != nil && (pos < 0 || pos > len(tk.data)) {
+		tk.reload(tk.base + pos)
+		pos = 0
+	}
+	if pos < 0 { // discarded by CompactBuffer
+		pos = 0
+	}
+	tk.setPosition(pos)
+}
+
+// setPosition is the same as SetPosition, but
+// uses a position relative to the internal buffer
+func (tk *Tokenizer) setPosition(pos int) {
 	// Internally, there are two cases where NextToken() is not sufficient:
 	// at the start (aToken and aaToken are empty)
 	// end after skipping over bytes (aToken and aaToken are invalid)
@@ -257,9 +687,12 @@ func (tk *Tokenizer) SetPosition(pos int) {
 	// (in the contrary, NextToken only does 1).
 	tk.currentPos = pos
 	tk.pos = pos
-	tk.aToken, tk.aError = tk.nextToken(Token{})
+	tk.lastToken, tk.lastError = Token{}, nil
+	tk.depth = 0
+	tk.unread.ok = false
+	tk.aToken, tk.aError = tk.tokenize(Token{})
 	tk.nextPos = tk.pos
-	tk.aaToken, tk.aaError = tk.nextToken(tk.aToken)
+	tk.aaToken, tk.aaError = tk.tokenize(tk.aToken)
 }
 
 // PeekToken reads a token but does not advance the position.
@@ -275,6 +708,46 @@ func (pr Tokenizer) PeekPeekToken() (Token, error) {
 	return pr.aaToken, pr.aaError
 }
 
+// PeekCouldBeObjHeader returns true if the next three tokens
+// are an indirect object header (like 12 0 obj), without consuming them.
+func (pr *Tokenizer) PeekCouldBeObjHeader() bool {
+	if pr.aError != nil || pr.aaError != nil || pr.aToken.Kind != Integer || pr.aaToken.Kind != Integer {
+		return false
+	}
+	// read one more token and go back
+	pos, reuse := pr.pos, pr.ReuseValues
+	pr.ReuseValues = false // the buffers are used by the cached tokens
+	tk, err := pr.tokenize(pr.aaToken)
+	pr.pos, pr.ReuseValues = pos, reuse
+	return err == nil && tk.IsOther("obj")
+}
+
+// PeekN returns the token `n` positions after the current one, without advancing:
+// PeekN(0) is the same as `PeekToken` and PeekN(1) as `PeekPeekToken`.
+// For n >= 2, the tokens are not cached, but read again at each call.
+// As `NextToken`, it stops at binary streams and inline data, returning EOF.
+func (pr *Tokenizer) PeekN(n int) (Token, error) {
+	if n < 0 {
+		return Token{}, fmt.Errorf("invalid negative lookahead %d", n)
+	}
+	if n == 0 {
+		return pr.aToken, pr.aError
+	}
+	tk, err := pr.aaToken, pr.aaError
+	// read the additional tokens and go back
+	pos, reuse := pr.pos, pr.ReuseValues
+	pr.ReuseValues = false // the buffers are used by the cached tokens
+	for i := 1; i < n && err == nil && tk.Kind != EOF; i++ {
+		if tk.startsBinary() {
+			tk = Token{Kind: EOF}
+			break
+		}
+		tk, err = pr.tokenize(tk)
+	}
+	pr.pos, pr.ReuseValues = pos, reuse
+	return tk, err
+}
+
 func (pr Tokenizer) IsEOF() bool {
 	tk, _ := pr.PeekToken() // delay the error checking
 	return tk.Kind == EOF
@@ -283,6 +756,12 @@ func (pr Tokenizer) IsEOF() bool {
 // NextToken reads a token and advances (consuming the token).
 // If EOF is reached, no error is returned, but an `EOF` token.
 func (pr *Tokenizer) NextToken() (Token, error) {
+	// the consumed token is kept in lastToken: only the
+	// previous one has to be saved to support UnreadToken
+	u := &pr.unread
+	u.lastToken, u.lastError = pr.lastToken, pr.lastError
+	u.currentPos, u.nbTokens, u.depth, u.ok = pr.currentPos, pr.nbTokens, pr.depth, true
+
 	tk, err := pr.PeekToken()                     // n+1 to n
 	pr.aToken, pr.aError = pr.aaToken, pr.aaError // n+2 to n+1
 	pr.currentPos = pr.nextPos                    // n+1 to n
@@ -295,16 +774,197 @@ func (pr *Tokenizer) NextToken() (Token, error) {
 	if pr.aaToken.startsBinary() {
 		pr.aaToken, pr.aaError = Token{Kind: EOF}, nil
 	} else {
-		pr.aaToken, pr.aaError = pr.nextToken(pr.aaToken) // read the n+3 and store it in n+2
+		pr.aaToken, pr.aaError = pr.tokenize(pr.aaToken) // read the n+3 and store it in n+2
+	}
+
+	switch tk.Kind {
+	case StartArray, StartDic, StartProc:
+		pr.depth++
+	case EndArray, EndDic, EndProc:
+		if pr.depth > 0 {
+			pr.depth--
+		}
 	}
+
+	if err == nil && tk.Kind != EOF {
+		pr.nbTokens++
+		if pr.MaxTokens > 0 && pr.nbTokens > pr.MaxTokens {
+			pr.unread.ok = false // the token is lost
+			tk, err = Token{}, errorAt(pr.CurrentPosition(), "maximum number of tokens (%d) exceeded", pr.MaxTokens)
+		}
+	}
+
+	pr.lastToken, pr.lastError = tk, err
 	return tk, err
 }
 
+// NextTokenCtx is the same as `NextToken`, but checks `ctx` before
+// each read of the source in reader mode, returning the context error
+// when it is done. Note that a pending Read is not interrupted.
+// On cancellation, the tokenizer is left as before the call.
+func (pr *Tokenizer) NextTokenCtx(ctx context.Context) (Token, error) {
+	if err := ctx.Err(); err != nil {
+		return Token{}, err
+	}
+	pr.ctx = ctx
+	tk, err := pr.NextToken()
+	pr.ctx = nil
+	if pr.ctxErr != nil { // the lookahead token may be truncated
+		err = pr.ctxErr
+		pr.ctxErr = nil
+		pr.UnreadToken()
+		return Token{}, err
+	}
+	return tk, err
+}
+
+// NextTokenRequired is the same as `NextToken`, but returns
+// an error instead of an EOF token, when more data is expected.
+func (pr *Tokenizer) NextTokenRequired() (Token, error) {
+	tk, err := pr.NextToken()
+	if err == nil && tk.Kind == EOF {
+		return Token{}, errorAt(pr.CurrentPosition(), "unexpected EOF")
+	}
+	return tk, err
+}
+
+// ExpectOneOf reads the next token and returns an error
+// if its kind is not one of `kinds`.
+func (pr *Tokenizer) ExpectOneOf(kinds ...Kind) (Token, error) {
+	tk, err := pr.NextToken()
+	if err != nil {
+		return Token{}, err
+	}
+	for _, k := range kinds {
+		if tk.Kind == k {
+			return tk, nil
+		}
+	}
+	names := make([]string, len(kinds))
+	for i, k := range kinds {
+		names[i] = k.String()
+	}
+	return Token{}, errorAt(pr.CurrentPosition(), "expected one of %s, got %s", strings.Join(names, ", "), tk.Kind)
+}
+
+// NextTokenWithTerminator is the same as `NextToken`, but also returns
+// the byte following the token, which is the delimiter ending
+// names, numbers and keywords.
+// `hasTerminator` is false if the token ends the input.
+func (pr *Tokenizer) NextTokenWithTerminator() (tk Token, terminator byte, hasTerminator bool, err error) {
+	tk, err = pr.NextToken()
+	if err != nil || tk.Kind == EOF || pr.currentPos >= len(pr.data) {
+		return tk, 0, false, err
+	}
+	return tk, pr.data[pr.currentPos], true, nil
+}
+
+// unreadState stores what is needed to revert
+// the last call to NextToken, besides the consumed token
+// itself, which is still available in lastToken
+type unreadState struct {
+	lastToken Token
+	lastError error
+
+	currentPos, nbTokens, depth int
+
+	ok bool
+}
+
+// UnreadToken reverts the last call to `NextToken`, so that
+// the next call to `NextToken` (or `PeekToken`) returns the same token again.
+// Only one level is supported: an error is returned if no token
+// has been read since the last call to `UnreadToken` or `SetPosition`,
+// or if the last call failed because of the `MaxTokens` limit.
+func (pr *Tokenizer) UnreadToken() error {
+	u := pr.unread
+	if !u.ok {
+		return errors.New("no token to unread")
+	}
+	pr.aaToken, pr.aaError = pr.aToken, pr.aError
+	pr.aToken, pr.aError = pr.lastToken, pr.lastError
+	pr.pos = pr.nextPos // end of the (restored) aaToken
+	pr.nextPos = pr.currentPos
+	pr.currentPos = u.currentPos
+	pr.lastToken, pr.lastError = u.lastToken, u.lastError
+	pr.nbTokens, pr.depth = u.nbTokens, u.depth
+	pr.unread.ok = false
+	return nil
+}
+
+// AtTopLevel returns true if the tokens consumed by `NextToken`
+// are balanced, that is if the tokenizer is not inside
+// an array, a dictionary or a proc.
+// The nesting depth is reset by `SetPosition`.
+func (pr Tokenizer) AtTopLevel() bool { return pr.depth == 0 }
+
+// NextTokenSkipping reads tokens until one whose Kind is not in `kinds`
+// is found, and returns it.
+// EOF tokens are never skipped.
+func (pr *Tokenizer) NextTokenSkipping(kinds ...Kind) (Token, error) {
+	for {
+		tk, err := pr.NextToken()
+		if err != nil || tk.Kind == EOF {
+			return tk, err
+		}
+		skip := false
+		for _, k := range kinds {
+			if tk.Kind == k {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			return tk, nil
+		}
+	}
+}
+
+// LastToken returns the token (and error) returned by the
+// last call to `NextToken`.
+// It is cleared by `SetPosition` (and `Reset`), and then returns
+// a zero token.
+func (pr Tokenizer) LastToken() (Token, error) {
+	return pr.lastToken, pr.lastError
+}
+
+// Scan advances to the next token, which is then available
+// with `Token`, as `bufio.Scanner` does.
+// It returns false at EOF or on error, which is then returned by `Err`.
+//
+// A typical loop is :
+//
+//	for tk.Scan() {
+//		t := tk.Token()
+//		...
+//	}
+//	if err := tk.Err(); err != nil {
+//		...
+//	}
+func (pr *Tokenizer) Scan() bool {
+	if pr.lastError != nil {
+		return false
+	}
+	tk, err := pr.NextToken()
+	return err == nil && tk.Kind != EOF
+}
+
+// Token returns the token read by the last call to `Scan`
+// (that is, `LastToken`).
+func (pr Tokenizer) Token() Token { return pr.lastToken }
+
+// Err returns the error encountered by `Scan`, if any.
+func (pr Tokenizer) Err() error { return pr.lastError }
+
 // StreamPosition returns the position of the
 // begining of a stream, taking into account
 // white spaces.
 // See 7.3.8.1 - General
 func (pr *Tokenizer) StreamPosition() int {
+	return pr.base + pr.streamPos()
+}
+
+func (pr *Tokenizer) streamPos() int {
 	// The keyword stream that follows the stream dictionary shall be followed by an end-of-line marker
 	// consisting of either a CARRIAGE RETURN and a LINE FEED or just a LINE FEED, and not by a CARRIAGE
 	// RETURN alone
@@ -323,15 +983,21 @@ func (pr *Tokenizer) StreamPosition() int {
 
 // SkipBytes skips the next `n` bytes and return them. This method is useful
 // to handle inline data.
-// If `n` is too large, it will be truncated: no additional buffering is done.
+// In reader mode, the internal buffer is grown as needed.
+// If `n` is too large, it will be truncated.
 func (pr *Tokenizer) SkipBytes(n int) []byte {
 	// use currentPos, which is the position 'expected' by the caller
+	if n < 0 {
+		n = 0
+	} else if n > maxInt-pr.currentPos {
+		n = maxInt - pr.currentPos
+	}
 	target := pr.currentPos + n
-	if target > len(pr.data) { // truncate if needed
+	if !pr.growTo(target) { // truncate if needed
 		target = len(pr.data)
 	}
 	out := pr.data[pr.currentPos:target]
-	pr.SetPosition(target)
+	pr.setPosition(target)
 	return out
 }
 
@@ -345,6 +1011,64 @@ func (pr Tokenizer) Bytes() []byte {
 	return pr.data[pr.currentPos:]
 }
 
+// Remaining returns the number of bytes after the current position,
+// that is len(Bytes()), without slicing the input.
+// With an io.ReaderAt, the whole input size is used, but
+// with an io.Reader, only the current internal buffer is considered.
+func (pr Tokenizer) Remaining() int {
+	if pr.ra != nil {
+		return int(pr.raSize) - pr.CurrentPosition()
+	}
+	if pr.currentPos >= len(pr.data) {
+		return 0
+	}
+	return len(pr.data) - pr.currentPos
+}
+
+// Consumed returns the number of bytes consumed so far,
+// which is the same as `CurrentPosition`.
+// In memory mode, Consumed() + Remaining() is the length of the input.
+func (pr Tokenizer) Consumed() int { return pr.CurrentPosition() }
+
+// CompactBuffer discards the bytes before the current position, so that,
+// in reader mode, the memory used stays proportional to the current object.
+// Positions are still offsets in the whole input, but it is then not possible to go
+// back before the current position, and the slices previously returned by
+// `Bytes` or `SkipBytes` must not be used anymore.
+func (pr *Tokenizer) CompactBuffer() {
+	n := pr.currentPos
+	if n == 0 {
+		return
+	}
+	if pr.src != nil && pr.ZeroCopy {
+		// the tokens point into the buffer: use a new one
+		pr.data = append(make([]byte, 0, cap(pr.data)), pr.data[n:]...)
+	} else if pr.src != nil { // reuse the buffer we own
+		L := copy(pr.data, pr.data[n:])
+		pr.data = pr.data[:L]
+	} else {
+		pr.data = pr.data[n:]
+	}
+	pr.base += n
+	pr.pos -= n
+	pr.currentPos -= n
+	pr.nextPos -= n
+	pr.unread.ok = false
+	pr.lines.reset()
+}
+
+// RemainingReader returns a reader over the input, starting
+// from the current position.
+// In reader mode, the internal buffer is read first, then the source.
+// The tokenizer should not be used while reading from the returned reader.
+func (pr Tokenizer) RemainingReader() io.Reader {
+	rem := bytes.NewReader(pr.Bytes())
+	if pr.src == nil {
+		return rem
+	}
+	return io.MultiReader(rem, pr.src)
+}
+
 // IsHexChar converts a hex character into its value and a success flag
 // (see encoding/hex for details).
 func IsHexChar(c byte) (uint8, bool) {
@@ -359,12 +1083,24 @@ func IsHexChar(c byte) (uint8, bool) {
 	return c, false
 }
 
-const bufferSize = 1024 // should be enough for many pdf objects
+const defaultBufferSize = 1024 // should be enough for many pdf objects
+
+const maxInt = int(^uint(0) >> 1)
+
+// bufferSize returns the size of the reads in reader mode
+func (pr *Tokenizer) bufferSize() int {
+	if pr.ReaderBufferSize > 0 {
+		return pr.ReaderBufferSize
+	}
+	return defaultBufferSize
+}
+
+const maxNameLength = 127 // in bytes, see Annex C.1
 
 // return false if EOF, true if the moved forward
 func (pr *Tokenizer) read() (byte, bool) {
 	if pr.pos >= len(pr.data) && pr.src != nil { // try and grow
-		pr.grow(bufferSize)
+		pr.grow(pr.bufferSize())
 	}
 	if pr.pos >= len(pr.data) { // should not happen when pr.src != nil
 		return 0, false
@@ -375,8 +1111,13 @@ func (pr *Tokenizer) read() (byte, bool) {
 }
 
 // HasEOLBeforeToken checks if EOL happens before the next token.
-func (pr Tokenizer) HasEOLBeforeToken() bool {
-	for i := pr.currentPos; i < len(pr.data); i++ {
+// In reader mode, the internal buffer is grown as needed.
+func (pr *Tokenizer) HasEOLBeforeToken() bool {
+	for i := pr.currentPos; ; i++ {
+		// the data after binary markers may not be buffered yet
+		if !pr.growTo(i + 1) {
+			return false
+		}
 		if !IsAsciiWhitespace(pr.data[i]) {
 			break
 		}
@@ -389,57 +1130,128 @@ func (pr Tokenizer) HasEOLBeforeToken() bool {
 
 // CurrentPosition return the position in the input.
 // It may be used to go back if needed, using `SetPosition`.
-func (pr Tokenizer) CurrentPosition() int { return pr.currentPos }
+func (pr Tokenizer) CurrentPosition() int { return pr.base + pr.currentPos }
+
+// tokenize wraps nextToken, reporting error positions
+// as offsets in the input
+func (pr *Tokenizer) tokenize(previous Token) (Token, error) {
+	if pr.ReuseValues {
+		pr.valuesIndex = (pr.valuesIndex + 1) % len(pr.values)
+		pr.values[pr.valuesIndex] = pr.values[pr.valuesIndex][:0]
+	}
+	tk, err := pr.nextToken(previous)
+	if te, ok := err.(*TokenizerError); ok {
+		te.Pos += pr.base
+	}
+	return tk, err
+}
 
 // reads and advances, mutating `pos`
 func (pr *Tokenizer) nextToken(previous Token) (Token, error) {
+	triviaStart := pr.pos
 	ch, ok := pr.read()
-	for ok && IsAsciiWhitespace(ch) {
-		ch, ok = pr.read()
+	for {
+		for ok && IsAsciiWhitespace(ch) {
+			ch, ok = pr.read()
+		}
+		if ok && pr.SkipBOM {
+			if n := pr.bomLength(ch); n != 0 {
+				pr.pos += n - 1
+				ch, ok = pr.read()
+				continue
+			}
+		}
+		if !ok || ch != '%' || pr.EmitComments {
+			break
+		}
+		// ignore comments: go to next token
+		for ok && ch != '\r' && ch != '\n' {
+			ch, ok = pr.read()
+		}
+	}
+	if pr.CaptureTrivia {
+		end := pr.pos
+		if ok {
+			end--
+		}
+		pr.addTrivia(triviaStart, end)
 	}
 	if !ok {
 		return Token{Kind: EOF}, nil
 	}
 
 	var outBuf []byte
+	if pr.ReuseValues {
+		outBuf = pr.values[pr.valuesIndex]
+	}
 	switch ch {
 	case '[':
 		return Token{Kind: StartArray}, nil
 	case ']':
 		return Token{Kind: EndArray}, nil
 	case '{':
+		if pr.Strict {
+			return Token{}, psOnlyError("procedure", pr.pos-1)
+		}
 		return Token{Kind: StartProc}, nil
 	case '}':
+		if pr.Strict {
+			return Token{}, psOnlyError("procedure", pr.pos-1)
+		}
 		return Token{Kind: EndProc}, nil
 	case '/':
+		start := pr.pos - 1
+		nbEscapes := 0
 		for {
 			ch, ok = pr.read()
+			if ok && ch == 0 && pr.Strict {
+				// NUL is a white space, but is likely to be binary garbage here
+				return Token{}, errorAt(pr.pos-1, "null byte in name")
+			}
 			if !ok || isDelimiter(ch) {
 				break
 			}
-			outBuf = append(outBuf, ch)
 			if ch == '#' {
-				h1, _ := pr.read()
-				h2, _ := pr.read()
-				_, err := hex.Decode([]byte{0}, []byte{h1, h2})
-				if err != nil {
-					return Token{}, errors.New("corrupted name object")
+				hashPos := pr.pos - 1
+				h1, ok1 := pr.read()
+				h2, ok2 := pr.read()
+				if !(ok1 && ok2) { // pos is at the end of the input
+					return Token{}, errorAt(hashPos, "truncated name escape")
 				}
-				outBuf = append(outBuf, h1, h2)
+				_, ok1 = IsHexChar(h1)
+				_, ok2 = IsHexChar(h2)
+				if !(ok1 && ok2) {
+					return Token{}, errorAt(hashPos, "corrupted name object")
+				}
+				nbEscapes++
+			}
+			if pr.MaxTokenSize > 0 && pr.pos-start-1 > pr.MaxTokenSize {
+				return Token{}, pr.tooLargeError(start)
 			}
 		}
 		// the delimiter may be important, dont skip it
 		if ok { // we moved, so its safe go back
 			pr.pos--
 		}
-		return Token{Kind: Name, Value: outBuf}, nil
+		// escapes are not decoded: the value is the raw name
+		raw := pr.data[start+1 : pr.pos]
+		// escape sequences count as one byte
+		if L := len(raw) - 2*nbEscapes; pr.Strict && L > maxNameLength {
+			return Token{}, errorAt(start, "name too long (%d bytes)", L)
+		}
+		return Token{Kind: Name, Value: pr.value(raw)}, nil
 	case '>':
+		start := pr.pos - 1
 		ch, ok = pr.read()
+		if !ok { // pos is unchanged
+			return Token{}, errorAt(start, "unexpected EOF after '>'")
+		}
 		if ch != '>' {
-			return Token{}, errors.New("'>' not expected")
+			return Token{}, errorAt(start, "'>' not expected")
 		}
 		return Token{Kind: EndDic}, nil
 	case '<':
+		start := pr.pos - 1
 		v1, ok1 := pr.read()
 		if v1 == '<' {
 			return Token{Kind: StartDic}, nil
@@ -452,39 +1264,59 @@ func (pr *Tokenizer) nextToken(previous Token) (Token, error) {
 			for ok1 && IsAsciiWhitespace(v1) {
 				v1, ok1 = pr.read()
 			}
+			if !ok1 {
+				return Token{}, errorAt(start, "unexpected EOF in hex string")
+			}
 			if v1 == '>' {
 				break
 			}
 			v1, ok1 = IsHexChar(v1)
 			if !ok1 {
-				return Token{}, fmt.Errorf("invalid hex char %d (%s)", v1, string(rune(v1)))
+				return Token{}, errorAt(pr.pos-1, "invalid hex char %d (%s)", v1, string(rune(v1)))
 			}
 			v2, ok2 = pr.read()
 			for ok2 && IsAsciiWhitespace(v2) {
 				v2, ok2 = pr.read()
 			}
+			if !ok2 {
+				return Token{}, errorAt(start, "unexpected EOF in hex string")
+			}
 			if v2 == '>' {
+				if pr.StrictHexLength {
+					return Token{}, errorAt(pr.pos-1, "odd number of digits in hex string")
+				}
 				ch = v1 << 4
 				outBuf = append(outBuf, ch)
 				break
 			}
 			v2, ok2 = IsHexChar(v2)
 			if !ok2 {
-				return Token{}, fmt.Errorf("invalid hex char %d", v2)
+				return Token{}, errorAt(pr.pos-1, "invalid hex char %d", v2)
 			}
 			ch = (v1 << 4) + v2
 			outBuf = append(outBuf, ch)
+			if pr.exceedsMaxSize(outBuf) {
+				return Token{}, pr.tooLargeError(start)
+			}
 			v1, ok1 = pr.read()
 		}
-		return Token{Kind: StringHex, Value: outBuf}, nil
-	case '%':
+		return Token{Kind: StringHex, Value: pr.keepValue(outBuf)}, nil
+	case '%': // only reached with EmitComments
+		start := pr.pos - 1
 		ch, ok = pr.read()
 		for ok && ch != '\r' && ch != '\n' {
+			if pr.MaxTokenSize > 0 && pr.pos-start-1 > pr.MaxTokenSize {
+				return Token{}, pr.tooLargeError(start)
+			}
 			ch, ok = pr.read()
 		}
-		// ignore comments: go to next token
-		return pr.nextToken(previous)
+		end := pr.pos
+		if ok { // EOL
+			end--
+		}
+		return Token{Kind: Comment, Value: pr.value(pr.data[start+1 : end])}, nil
 	case '(':
+		start := pr.pos - 1
 		nesting := 0
 		for {
 			ch, ok = pr.read()
@@ -513,7 +1345,7 @@ func (pr *Tokenizer) nextToken(previous Token) (Token, error) {
 				case '\r':
 					lineBreak = true
 					ch, ok = pr.read()
-					if ch != '\n' {
+					if ok && ch != '\n' {
 						pr.pos--
 					}
 				case '\n':
@@ -524,16 +1356,20 @@ func (pr *Tokenizer) nextToken(previous Token) (Token, error) {
 					}
 					octal := ch - '0'
 					ch, ok = pr.read()
-					if ch < '0' || ch > '7' {
-						pr.pos--
-						ch = octal
+					if !ok || ch < '0' || ch > '7' {
+						if ok { // at EOF, pos is unchanged
+							pr.pos--
+						}
+						ch, ok = octal, true
 						break
 					}
 					octal = (octal << 3) + ch - '0'
 					ch, ok = pr.read()
-					if ch < '0' || ch > '7' {
-						pr.pos--
-						ch = octal
+					if !ok || ch < '0' || ch > '7' {
+						if ok {
+							pr.pos--
+						}
+						ch, ok = octal, true
 						break
 					}
 					octal = (octal << 3) + ch - '0'
@@ -560,56 +1396,208 @@ func (pr *Tokenizer) nextToken(previous Token) (Token, error) {
 				break
 			}
 			outBuf = append(outBuf, ch)
+			if pr.exceedsMaxSize(outBuf) {
+				return Token{}, pr.tooLargeError(start)
+			}
 		}
 		if !ok {
-			return Token{}, errors.New("error reading string: unexpected EOF")
+			return Token{}, errorAt(start, "error reading string: unexpected EOF in string started")
+		}
+		if pr.RawStrings { // content between the outer parenthesis
+			return Token{Kind: String, Value: pr.value(pr.data[start+1 : pr.pos-1])}, nil
 		}
-		return Token{Kind: String, Value: outBuf}, nil
+		return Token{Kind: String, Value: pr.keepValue(outBuf)}, nil
 	default:
 		pr.pos-- // we need the test char
-		var token Token
-		if token, ok = pr.readNumber(); ok {
+		token, ok, err := pr.readNumber()
+		if err != nil {
+			return Token{}, err
+		}
+		if ok {
 			return token, nil
 		}
-		ch, ok = pr.read() // we went back before parsing a number
-		outBuf = append(outBuf, ch)
+		start := pr.pos
+		pr.read() // we went back before parsing a number
 		ch, ok = pr.read()
 		for !isDelimiter(ch) {
-			outBuf = append(outBuf, ch)
+			if pr.MaxTokenSize > 0 && pr.pos-start > pr.MaxTokenSize {
+				return Token{}, pr.tooLargeError(start)
+			}
 			ch, ok = pr.read()
 		}
 		if ok {
 			pr.pos--
 		}
+		outBuf = pr.value(pr.data[start:pr.pos])
 
-		if cmd := string(outBuf); cmd == "RD" || cmd == "-|" {
+		if pr.isCharStringCommand(outBuf) {
+			if pr.Strict {
+				return Token{}, psOnlyError("charstring", pr.pos-len(outBuf))
+			}
 			// return the next CharString instead
 			if previous.Kind == Integer {
 				f, err := previous.Int()
 				if err != nil {
-					return Token{}, fmt.Errorf("invalid charstring length: %s", err)
+					return Token{}, errorAt(pr.pos-len(outBuf), "invalid charstring length: %s", err)
 				}
-				return pr.readCharString(f), nil
+				return pr.readCharString(f, outBuf)
 			} else {
-				return Token{}, errors.New("expected INTEGER before -| or RD")
+				return Token{}, errorAt(pr.pos-len(outBuf), "expected INTEGER before -| or RD, got %s", previous.Kind)
+			}
+		}
+		if pr.CoalesceSignedNumbers && len(outBuf) == 1 && (outBuf[0] == '-' || outBuf[0] == '+') {
+			if token, ok := pr.readSeparatedNumber(outBuf[0]); ok {
+				return token, nil
 			}
 		}
 		return Token{Kind: Other, Value: outBuf}, nil
 	}
 }
 
+// isCharStringCommand returns true if `cmd` introduces
+// binary charstring data
+func (pr *Tokenizer) isCharStringCommand(cmd []byte) bool {
+	if len(pr.CharStringCommands) == 0 {
+		return string(cmd) == "RD" || string(cmd) == "-|"
+	}
+	for _, c := range pr.CharStringCommands {
+		if string(cmd) == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Trivia is a run of white spaces and comments
+// found between two tokens.
+type Trivia struct {
+	Pos   int // offset in the input
+	Value []byte
+}
+
+// Trivia returns the white spaces and comments found between tokens,
+// when the `CaptureTrivia` option is set.
+// Since the tokenizer reads two tokens ahead, it includes the trivia
+// before the next two tokens.
+// After a jump backward (see `SetPosition`), the trivia after
+// the new position are captured again.
+func (pr Tokenizer) Trivia() []Trivia { return pr.trivia }
+
+// addTrivia records data[start:end] as trivia,
+// discarding the trivia previously recorded after `start`.
+func (pr *Tokenizer) addTrivia(start, end int) {
+	abs := pr.base + start
+	for len(pr.trivia) > 0 && pr.trivia[len(pr.trivia)-1].Pos >= abs {
+		pr.trivia = pr.trivia[:len(pr.trivia)-1]
+	}
+	if start == end {
+		return
+	}
+	pr.trivia = append(pr.trivia, Trivia{Pos: abs, Value: copyBytes(pr.data[start:end])})
+}
+
+// bomLength returns the length of the byte order mark
+// starting with `ch` (just read), or 0.
+func (pr *Tokenizer) bomLength(ch byte) int {
+	if ch != 0xEF && ch != 0xFE && ch != 0xFF {
+		return 0
+	}
+	if pr.pos+2 > len(pr.data) && pr.src != nil {
+		pr.grow(pr.bufferSize())
+	}
+	next := pr.data[pr.pos:]
+	switch {
+	case ch == 0xEF && len(next) >= 2 && next[0] == 0xBB && next[1] == 0xBF: // UTF-8
+		return 3
+	case ch == 0xFE && len(next) >= 1 && next[0] == 0xFF: // UTF-16 BE
+		return 2
+	case ch == 0xFF && len(next) >= 1 && next[0] == 0xFE: // UTF-16 LE
+		return 2
+	}
+	return 0
+}
+
+// readSeparatedNumber tries to read a number
+// after whitespaces, adding the given sign.
+// If no unsigned number is found, the position is restored.
+func (pr *Tokenizer) readSeparatedNumber(sign byte) (Token, bool) {
+	markedPos := pr.pos
+	ch, ok := pr.read()
+	for ok && IsAsciiWhitespace(ch) {
+		ch, ok = pr.read()
+	}
+	if ok && ch != '+' && ch != '-' {
+		pr.pos-- // we need the first char
+		if token, ok, _ := pr.readNumber(); ok {
+			token.Value = append([]byte{sign}, token.Value...)
+			return token, true
+		}
+	}
+	pr.pos = markedPos
+	return Token{}, false
+}
+
+// readHooked calls ParseNumberHook with the next regular token,
+// advancing if the hook accepts it.
+func (pr *Tokenizer) readHooked() (Token, bool, error) {
+	start := pr.pos
+	pr.numberSb = pr.numberSb[:0]
+	ch, ok := pr.read()
+	for ok && !isDelimiter(ch) {
+		pr.numberSb = append(pr.numberSb, ch)
+		if pr.exceedsMaxSize(pr.numberSb) {
+			return Token{}, false, pr.tooLargeError(start)
+		}
+		ch, ok = pr.read()
+	}
+	if ok {
+		pr.pos--
+	}
+	if len(pr.numberSb) == 0 {
+		return Token{}, false, nil
+	}
+	token, ok := pr.ParseNumberHook(pr.numberSb)
+	return token, ok, nil
+}
+
+func (pr *Tokenizer) exceedsMaxSize(buf []byte) bool {
+	return pr.MaxTokenSize > 0 && len(buf) > pr.MaxTokenSize
+}
+
+// tooLargeError is returned for a token started at `start`
+// exceeding `MaxTokenSize`
+func (pr *Tokenizer) tooLargeError(start int) error {
+	return errorAt(start, "token exceeds maximum size (%d bytes)", pr.MaxTokenSize)
+}
+
+// psOnlyError is returned in strict mode, when
+// a PostScript only construct is found at `pos`.
+func psOnlyError(construct string, pos int) error {
+	return errorAt(pos, "PostScript only construct (%s)", construct)
+}
+
 // accept PS syntax (radix and exponents)
-// return false if it is not a number
-func (pr *Tokenizer) readNumber() (Token, bool) {
+// return false if it is not a number, and an error
+// for invalid radix numbers
+func (pr *Tokenizer) readNumber() (Token, bool, error) {
 	markedPos := pr.pos
 
+	if pr.ParseNumberHook != nil {
+		if token, ok, err := pr.readHooked(); err != nil {
+			return Token{}, false, err
+		} else if ok {
+			return token, true, nil
+		}
+		pr.pos = markedPos
+	}
+
 	pr.numberSb = pr.numberSb[:0]
-	var radix string
 
 	c, ok := pr.read() // one char is OK
 	hasDigit := false
 	// optional + or -
-	if c == '+' || c == '-' {
+	isSigned := c == '+' || c == '-'
+	if isSigned {
 		pr.numberSb = append(pr.numberSb, c)
 		c, _ = pr.read()
 	}
@@ -621,75 +1609,156 @@ func (pr *Tokenizer) readNumber() (Token, bool) {
 		hasDigit = true
 	}
 
-	numberRequired := true
 	// optional .
 	if c == '.' {
 		pr.numberSb = append(pr.numberSb, c)
 		c, ok = pr.read()
 		// a float may terminate after . (like in 4.)
-		numberRequired = false
+		for isDigit(c) {
+			pr.numberSb = append(pr.numberSb, c)
+			c, ok = pr.read()
+			hasDigit = true
+		}
+		if pr.StrictNumbers && c == '.' {
+			return Token{}, false, errorAt(markedPos, "invalid number %s.: multiple decimal points", pr.numberSb)
+		}
+		if !hasDigit || (c != 'E' && c != 'e') {
+			if ok {
+				pr.pos--
+			}
+			return Token{Value: pr.value(pr.data[markedPos:pr.pos]), Kind: Float}, true, nil
+		}
 	} else if c == '#' {
 		// PostScript radix number takes the form base#number
-		radix = string(pr.numberSb)
-		pr.numberSb = pr.numberSb[:0]
-		c, ok = pr.read()
+		if !hasDigit || isSigned {
+			// failure
+			pr.pos = markedPos
+			return Token{}, false, nil
+		}
+		if pr.Strict {
+			return Token{}, false, psOnlyError("radix number", markedPos)
+		}
+		return pr.readRadixNumber(markedPos)
 	} else if len(pr.numberSb) == 0 || !hasDigit {
 		// failure
 		pr.pos = markedPos
-		return Token{}, false
-	} else if c == 'E' || c == 'e' {
-		// optional minus
-		pr.numberSb = append(pr.numberSb, c)
-		c, ok = pr.read()
-		if c == '-' {
-			pr.numberSb = append(pr.numberSb, c)
-			c, ok = pr.read()
-		}
-	} else {
+		return Token{}, false, nil
+	} else if c != 'E' && c != 'e' {
 		// integer
 		if ok {
 			pr.pos--
 		}
-		return Token{Value: copyBytes(pr.numberSb), Kind: Integer}, true
+		return Token{Value: pr.value(pr.data[markedPos:pr.pos]), Kind: Integer}, true, nil
+	}
+
+	// exponent, with optional sign
+	pr.numberSb = append(pr.numberSb, c)
+	c, ok = pr.read()
+	if c == '-' || c == '+' {
+		pr.numberSb = append(pr.numberSb, c)
+		c, ok = pr.read()
 	}
 
 	// check required digit
-	if numberRequired && !isDigit(c) {
+	if !isDigit(c) {
 		// failure
 		pr.pos = markedPos
-		return Token{}, false
+		return Token{}, false, nil
 	}
 
-	// optional digits
 	for isDigit(c) {
 		pr.numberSb = append(pr.numberSb, c)
 		c, ok = pr.read()
 	}
+	if pr.StrictNumbers && c == '.' {
+		return Token{}, false, errorAt(markedPos, "invalid number %s.: decimal point in exponent", pr.numberSb)
+	}
 
 	if ok {
 		pr.pos--
 	}
-	if radix != "" {
-		intRadix, _ := strconv.Atoi(radix)
-		valInt, _ := strconv.ParseInt(string(pr.numberSb), intRadix, 0)
-		return Token{Value: []byte(strconv.Itoa(int(valInt))), Kind: Integer}, true
+	return Token{Value: pr.value(pr.data[markedPos:pr.pos]), Kind: Float}, true, nil
+}
+
+// readRadixNumber reads the digits of a number base#number, the base being
+// stored in `numberSb`.
+// The returned token has its value converted to decimal.
+// An error is returned for invalid base or digits.
+func (pr *Tokenizer) readRadixNumber(markedPos int) (Token, bool, error) {
+	radix := string(pr.numberSb)
+	pr.numberSb = pr.numberSb[:0]
+	c, ok := pr.read()
+	for isDigit(c) || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') {
+		pr.numberSb = append(pr.numberSb, c)
+		c, ok = pr.read()
+	}
+	if len(pr.numberSb) == 0 {
+		// failure
+		pr.pos = markedPos
+		return Token{}, false, nil
+	}
+	if ok {
+		pr.pos--
 	}
-	return Token{Value: copyBytes(pr.numberSb), Kind: Float}, true
+	intRadix, err := strconv.Atoi(radix)
+	if err != nil || intRadix < 2 || intRadix > 36 {
+		return Token{}, false, errorAt(markedPos, "invalid radix base %s", radix)
+	}
+	valInt, err := strconv.ParseInt(string(pr.numberSb), intRadix, 64)
+	if err != nil {
+		return Token{}, false, errorAt(markedPos, "invalid radix number %s#%s: %s", radix, pr.numberSb, err)
+	}
+	return Token{
+		Value: []byte(strconv.FormatInt(valInt, 10)),
+		Kind:  Integer,
+		Radix: uint8(intRadix),
+		meta:  &tokenMeta{radixDigits: copyBytes(pr.numberSb)},
+	}, true, nil
 }
 
 // reads a binary CharString.
-func (pr *Tokenizer) readCharString(length int) Token {
+func (pr *Tokenizer) readCharString(length int, command []byte) (Token, error) {
+	start := pr.pos - len(command)
+	if length < 0 {
+		return Token{}, errorAt(start, "invalid negative charstring length %d", length)
+	}
+	if pr.MaxTokenSize > 0 && length > pr.MaxTokenSize {
+		return Token{}, pr.tooLargeError(start)
+	}
 	pr.pos++ // space
 	maxL := pr.pos + length
-	if maxL >= len(pr.data) && pr.src != nil { // try to grow
-		pr.grow(maxL - len(pr.data))
+	if !pr.growTo(maxL) || maxL < pr.pos {
+		return Token{}, errorAt(start, "charstring length %d exceeds the remaining input", length)
 	}
-	if maxL >= len(pr.data) {
-		maxL = len(pr.data)
+	out := NewCharString(command, pr.value(pr.data[pr.pos:maxL]))
+	pr.pos = maxL
+	return out, nil
+}
+
+// value returns `raw`, a slice of the input, as a token value:
+// it is copied (into the reused buffers with ReuseValues) unless ZeroCopy is set.
+func (pr *Tokenizer) value(raw []byte) []byte {
+	if pr.ZeroCopy {
+		return raw[:len(raw):len(raw)] // protect the input from appends
 	}
-	out := Token{Value: copyBytes(pr.data[pr.pos:maxL]), Kind: CharString}
-	pr.pos += length
-	return out
+	if pr.ReuseValues {
+		buf := pr.values[pr.valuesIndex]
+		start := len(buf)
+		buf = append(buf, raw...)
+		pr.values[pr.valuesIndex] = buf
+		return buf[start:len(buf):len(buf)]
+	}
+	return copyBytes(raw)
+}
+
+// keepValue stores `buf`, built from the current value buffer,
+// so that it is reused by the next tokenizations.
+func (pr *Tokenizer) keepValue(buf []byte) []byte {
+	if pr.ReuseValues {
+		pr.values[pr.valuesIndex] = buf
+		return buf[:len(buf):len(buf)]
+	}
+	return buf
 }
 
 func copyBytes(src []byte) []byte {
@@ -185,5 +185,5 @@ func TestPS(t *testing.T) {
 	doTestParseObjectOK("8#1777 +16#FFFE -2#1000", t)
 
 	doTestParseObjectFail(false, "a RD ", t)
-	doTestParseObjectOK("12 RD 88", t) // accept bigger length and truncate
+	doTestParseObjectFail(false, "12 RD 88", t) // length exceeding the input
 }
@@ -2,10 +2,15 @@ package tokenizer
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"io"
 	"io/ioutil"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestCharString(t *testing.T) {
@@ -87,12 +92,12 @@ func TestConvert(t *testing.T) {
 }
 
 func TestStrings(t *testing.T) {
-	for i := range [CharString]int{} {
+	for i := range [Comment]int{} {
 		if Kind(i+1).String() == "<invalid token>" {
 			t.Error()
 		}
 	}
-	if Kind(CharString+1).String() != "<invalid token>" || Kind(0).String() != "<invalid token>" {
+	if Kind(Comment+1).String() != "<invalid token>" || Kind(0).String() != "<invalid token>" {
 		t.Error()
 	}
 }
@@ -199,6 +204,79 @@ func TestEOL(t *testing.T) {
 	}
 }
 
+// chunkReader returns one chunk per call to Read,
+// an empty chunk simulating a slow source
+type chunkReader []string
+
+func (cr *chunkReader) Read(p []byte) (int, error) {
+	if len(*cr) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, (*cr)[0])
+	*cr = (*cr)[1:]
+	return n, nil
+}
+
+func TestNextTokenCtx(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	nbReads := 0
+	src := chunkReader{"1 2 3", "4", "5 6"}
+	tk := NewTokenizerFromReader(readerFunc(func(p []byte) (int, error) {
+		nbReads++
+		if nbReads == 2 { // cancel while reading
+			cancel()
+		}
+		return src.Read(p)
+	}))
+
+	// the number 345 spans the three chunks
+	if _, err := tk.NextTokenCtx(ctx); err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+	if nbReads != 2 {
+		t.Errorf("expected 2 reads, got %d", nbReads)
+	}
+	if _, err := tk.NextTokenCtx(ctx); err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+
+	// the tokenizer is still usable
+	var got []string
+	for tk.Scan() {
+		got = append(got, string(tk.Token().Value))
+	}
+	if exp := []string{"1", "2", "345", "6"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (rf readerFunc) Read(p []byte) (int, error) { return rf(p) }
+
+func TestEOLReader(t *testing.T) {
+	for _, test := range []struct {
+		chunks chunkReader
+		exp    bool
+	}{
+		{chunkReader{"<< >> stream", "", "", " \t \r\nabc"}, true},
+		{chunkReader{"<< >> stream", "", "", " \t ", "abc\n"}, false},
+		{chunkReader{"<< >> stream", "", "", " \t "}, false},
+	} {
+		// the data after 'stream' is not buffered yet
+		tk := NewTokenizerFromReader(&test.chunks)
+		tk.NextToken()
+		tk.NextToken()
+		if tok, _ := tk.NextToken(); !tok.IsOther("stream") {
+			t.Fatalf("expected stream, got %v", tok)
+		}
+		if got := tk.HasEOLBeforeToken(); got != test.exp {
+			t.Errorf("expected %v, got %v", test.exp, got)
+		}
+	}
+}
+
 func TestStreamSpace(t *testing.T) {
 	inputs := [][]byte{
 		[]byte("stream\r\nsd2sssd3"),
@@ -244,3 +322,1517 @@ func TestReset(t *testing.T) {
 		t.Fatalf("expected %v, got %v", exp, got)
 	}
 }
+
+func TestInt64(t *testing.T) {
+	tks, err := Tokenize([]byte("9999999999999 9007199254740993 -9007199254740993 78. 4.7"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, exp := range []int64{9999999999999, 9007199254740993, -9007199254740993, 78, 4} {
+		got, err := tks[i].Int64()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != exp {
+			t.Errorf("expected %d, got %d", exp, got)
+		}
+	}
+
+	// integer stored with a float syntax
+	if i, err := (Token{Kind: Integer, Value: []byte("12.")}).Int64(); err != nil || i != 12 {
+		t.Errorf("expected 12, got %d (%v)", i, err)
+	}
+
+	u, err := tks[1].Uint64()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != 9007199254740993 {
+		t.Errorf("expected %d, got %d", uint64(9007199254740993), u)
+	}
+	if _, err = tks[2].Uint64(); err == nil {
+		t.Error("expected error for negative offset")
+	}
+	if _, err = (Token{Kind: Float, Value: []byte("-0.5")}).Uint64(); err == nil {
+		t.Error("expected error for negative offset")
+	}
+}
+
+func TestGenerationNumbers(t *testing.T) {
+	tks, err := Tokenize([]byte("0000000000 00000 n 0000000009 65535 f 12 00003 R"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, exp := range map[int]int{0: 0, 1: 0, 3: 9, 4: 65535, 6: 12, 7: 3} {
+		if tks[i].Kind != Integer {
+			t.Fatalf("expected Integer, got %s", tks[i].Kind)
+		}
+		got, err := tks[i].Int()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != exp {
+			t.Errorf("expected %d, got %d", exp, got)
+		}
+	}
+}
+
+func TestIntOverflow(t *testing.T) {
+	for _, tk := range []Token{
+		{Kind: Integer, Value: []byte("99999999999999999999")},
+		{Kind: Integer, Value: []byte("-99999999999999999999")},
+		{Kind: Float, Value: []byte("1e30")},
+	} {
+		_, err := tk.Int()
+		if err == nil {
+			t.Fatalf("expected overflow error for %s", tk.Value)
+		}
+		if !errors.Is(err, strconv.ErrRange) {
+			t.Errorf("expected strconv.ErrRange, got %v", err)
+		}
+	}
+
+	if _, err := (Token{Kind: Float, Value: []byte("1e30")}).Uint64(); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("expected strconv.ErrRange, got %v", err)
+	}
+
+	if _, err := Tokenize([]byte("99999999999999999999 RD")); err == nil {
+		t.Error("expected error for overflowing charstring length")
+	}
+}
+
+func TestMaxTokens(t *testing.T) {
+	input := []byte(strings.Repeat("1 /a (s) ", 100))
+
+	tk := NewTokenizer(input)
+	tk.MaxTokens = 10
+	for i := 0; i < 10; i++ {
+		if _, err := tk.NextToken(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := tk.NextToken(); err == nil {
+		t.Fatal("expected error when exceeding MaxTokens")
+	}
+	if err := tk.UnreadToken(); err == nil {
+		t.Fatal("expected error when unreading past MaxTokens")
+	}
+
+	if _, err := Tokenize(input, WithMaxTokens(10)); err == nil {
+		t.Fatal("expected error when exceeding MaxTokens")
+	}
+	if tks, err := Tokenize(input, WithMaxTokens(300)); err != nil || len(tks) != 300 {
+		t.Fatalf("expected 300 tokens, got %d (%v)", len(tks), err)
+	}
+
+	// the limit is kept after Reset
+	tk.Reset(input)
+	if _, err := tk.readAll(); err == nil {
+		t.Fatal("expected error when exceeding MaxTokens")
+	}
+
+	tk.MaxTokens = 0
+	tk.Reset(input)
+	tks, err := tk.readAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tks) != 300 {
+		t.Fatalf("expected 300 tokens, got %d", len(tks))
+	}
+}
+
+func TestCoalesceSignedNumbers(t *testing.T) {
+	input := []byte("- 5 + \n4.5 - (a) -")
+
+	tks, err := Tokenize(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tks) != 7 || !tks[0].IsOther("-") || tks[1].Kind != Integer {
+		t.Fatalf("unexpected tokens %v", tks)
+	}
+
+	tk := NewTokenizer(input)
+	tk.CoalesceSignedNumbers = true
+	tk.SetPosition(0)
+	tks, err = tk.readAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []Token{
+		{Kind: Integer, Value: []byte("-5")},
+		{Kind: Float, Value: []byte("+4.5")},
+		{Kind: Other, Value: []byte("-")},
+		{Kind: String, Value: []byte("a")},
+		{Kind: Other, Value: []byte("-")},
+	}
+	if !reflect.DeepEqual(tks, exp) {
+		t.Fatalf("expected %v, got %v", exp, tks)
+	}
+	if i, _ := tks[0].Int(); i != -5 {
+		t.Errorf("expected -5, got %d", i)
+	}
+}
+
+func TestBigInt(t *testing.T) {
+	const v = "-1234567890123456789012345678901234567890"
+	tks, err := Tokenize([]byte(v + " +42 4.5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := tks[0].BigInt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != v {
+		t.Errorf("expected %s, got %s", v, b)
+	}
+	b, err = tks[1].BigInt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Int64() != 42 {
+		t.Errorf("expected 42, got %s", b)
+	}
+	if _, err = tks[2].BigInt(); err == nil {
+		t.Error("expected error for Float token")
+	}
+}
+
+func TestRadix(t *testing.T) {
+	tks, err := Tokenize([]byte("16#FFFE 8#1777 2#1000 36#zz 255 16#ff/a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []struct {
+		token  Token
+		digits string
+	}{
+		{Token{Kind: Integer, Value: []byte("65534"), Radix: 16}, "FFFE"},
+		{Token{Kind: Integer, Value: []byte("1023"), Radix: 8}, "1777"},
+		{Token{Kind: Integer, Value: []byte("8"), Radix: 2}, "1000"},
+		{Token{Kind: Integer, Value: []byte("1295"), Radix: 36}, "zz"},
+		{Token{Kind: Integer, Value: []byte("255")}, ""},
+		{Token{Kind: Integer, Value: []byte("255"), Radix: 16}, "ff"},
+		{Token{Kind: Name, Value: []byte("a")}, ""},
+	}
+	if len(tks) != len(exp) {
+		t.Fatalf("expected %d tokens, got %v", len(exp), tks)
+	}
+	for i, exp := range exp {
+		got := tks[i]
+		if !got.Equal(exp.token) || got.Radix != exp.token.Radix || string(got.RadixDigits()) != exp.digits {
+			t.Errorf("expected %v (%d#%s), got %v (%d#%s)", exp.token, exp.token.Radix, exp.digits, got, got.Radix, got.RadixDigits())
+		}
+	}
+}
+
+func TestLastToken(t *testing.T) {
+	tk := NewTokenizer([]byte("/a 5 (s)"))
+	if last, _ := tk.LastToken(); last.Kind != 0 {
+		t.Errorf("expected zero token, got %v", last)
+	}
+	tk.NextToken()
+	second, err := tk.NextToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, err := tk.LastToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(last, second) {
+		t.Errorf("expected %v, got %v", second, last)
+	}
+	tk.SetPosition(0)
+	if last, _ := tk.LastToken(); last.Kind != 0 {
+		t.Errorf("expected zero token, got %v", last)
+	}
+}
+
+func TestInvalidRadix(t *testing.T) {
+	for _, input := range []string{"1#0", "37#10", "16#GG", "2#102", "0#1", "16#FFFFFFFFFFFFFFFFFF"} {
+		if _, err := Tokenize([]byte(input)); err == nil {
+			t.Errorf("expected error for %s", input)
+		}
+	}
+
+	// signed bases are not radix numbers
+	tks, err := Tokenize([]byte("-2#1000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tks) != 1 || !tks[0].IsOther("-2#1000") {
+		t.Errorf("unexpected tokens %v", tks)
+	}
+}
+
+func TestExponential(t *testing.T) {
+	tks, err := Tokenize([]byte("1e3 1.5E-2 150 1.5 -.5e1 6.02E23"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []Token{
+		{Kind: Float, Value: []byte("1e3")},
+		{Kind: Float, Value: []byte("1.5E-2")},
+		{Kind: Integer, Value: []byte("150")},
+		{Kind: Float, Value: []byte("1.5")},
+		{Kind: Float, Value: []byte("-.5e1")},
+		{Kind: Float, Value: []byte("6.02E23")},
+	}
+	if !reflect.DeepEqual(tks, exp) {
+		t.Fatalf("expected %v, got %v", exp, tks)
+	}
+	for i, isExp := range []bool{true, true, false, false, true, true} {
+		if tks[i].Exponential() != isExp {
+			t.Errorf("%v: expected %v, got %v", tks[i], isExp, tks[i].Exponential())
+		}
+	}
+	for i, fl := range []Fl{1000, 0.015, 150, 1.5, -5, 6.02e23} {
+		if f, _ := tks[i].Float(); f != fl {
+			t.Errorf("expected %v, got %v", fl, f)
+		}
+	}
+}
+
+func TestStrictNumbers(t *testing.T) {
+	for _, input := range []string{"1.2.3", "1..2", "1.2e3.4", "1e3.4", "-.5."} {
+		if _, err := Tokenize([]byte(input)); err != nil {
+			t.Errorf("unexpected error in lenient mode: %s", err)
+		}
+
+		tk := NewTokenizer([]byte(input))
+		tk.StrictNumbers = true
+		tk.SetPosition(0)
+		if _, err := tk.readAll(); err == nil {
+			t.Errorf("expected error for %s", input)
+		}
+	}
+
+	tk := NewTokenizer([]byte("1.2 .3 4. 1e3 [1.5]"))
+	tk.StrictNumbers = true
+	tk.SetPosition(0)
+	tks, err := tk.readAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tks) != 7 {
+		t.Errorf("unexpected tokens %v", tks)
+	}
+}
+
+func TestExponentPlus(t *testing.T) {
+	tks, err := Tokenize([]byte("2E+2 2E+0 1.0E+5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, fl := range []Fl{200, 2, 1e5} {
+		if tks[i].Kind != Float {
+			t.Errorf("expected Float, got %s", tks[i].Kind)
+		}
+		if f, err := tks[i].Float(); err != nil || f != fl {
+			t.Errorf("expected %v, got %v (%v)", fl, f, err)
+		}
+	}
+
+	tks, err = Tokenize([]byte("2E+ 5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tks) != 2 || !tks[0].IsOther("2E+") {
+		t.Errorf("unexpected tokens %v", tks)
+	}
+}
+
+func TestAtTopLevel(t *testing.T) {
+	tk := NewTokenizer([]byte("/A << /B [ 1 { 2 } ] >> 3 ]"))
+	if !tk.AtTopLevel() {
+		t.Error("expected top level at start")
+	}
+	for _, exp := range []bool{
+		true,  // /A
+		false, // <<
+		false, // /B
+		false, // [
+		false, // 1
+		false, // {
+		false, // 2
+		false, // }
+		false, // ]
+		true,  // >>
+		true,  // 3
+		true,  // unbalanced ]
+	} {
+		tok, err := tk.NextToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tk.AtTopLevel(); got != exp {
+			t.Errorf("after %v: expected %v, got %v", tok, exp, got)
+		}
+	}
+}
+
+func TestStrict(t *testing.T) {
+	for _, test := range []struct {
+		input     string
+		construct string
+		pos       int
+	}{
+		{"1 {2} if", "procedure", 2},
+		{"[1 } 2", "procedure", 3},
+		{"/a 16#FF", "radix number", 3},
+		{"/a 4 RD abcd", "charstring", 5},
+		{"/a 4 -| abcd", "charstring", 5},
+	} {
+		// valid in lenient mode
+		if _, err := Tokenize([]byte(test.input)); err != nil {
+			t.Fatal(err)
+		}
+
+		tk := NewTokenizer([]byte(test.input))
+		tk.Strict = true
+		tk.SetPosition(0)
+		_, err := tk.readAll()
+		if err == nil {
+			t.Fatalf("expected error for %s", test.input)
+		}
+		if !strings.Contains(err.Error(), test.construct) || !strings.Contains(err.Error(), strconv.Itoa(test.pos)) {
+			t.Errorf("expected error with %s at %d, got %s", test.construct, test.pos, err)
+		}
+	}
+
+	tk := NewTokenizer([]byte("<< /A [1 2.5 (s) <ab>] >> 1 0 obj"))
+	tk.Strict = true
+	tk.SetPosition(0)
+	if _, err := tk.readAll(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestErrorPosition(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		pos   int
+		msg   string
+	}{
+		{"/a /Na#2me", 6, "corrupted name object"},
+		{"1 2 > 3", 4, "'>' not expected"},
+		{"[<0ab1 X>]", 7, "invalid hex char"},
+		{"[<0ab1 4X>]", 8, "invalid hex char"},
+		{"(abc", 0, "unexpected EOF"},
+		{"/a RD", 3, "expected INTEGER before -| or RD"},
+		{"1 2 37#10", 4, "invalid radix base"},
+	} {
+		_, err := Tokenize([]byte(test.input))
+		var tkErr *TokenizerError
+		if !errors.As(err, &tkErr) {
+			t.Fatalf("expected TokenizerError, got %v", err)
+		}
+		if tkErr.Pos != test.pos {
+			t.Errorf("%s: expected error at %d, got %d", test.input, test.pos, tkErr.Pos)
+		}
+		if !strings.Contains(err.Error(), test.msg) {
+			t.Errorf("expected error containing %s, got %s", test.msg, err)
+		}
+	}
+}
+
+func TestStrictHexLength(t *testing.T) {
+	tks, err := Tokenize([]byte("<ABC>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []byte{0xAB, 0xC0}; !bytes.Equal(tks[0].Value, exp) {
+		t.Errorf("expected %v, got %v", exp, tks[0].Value)
+	}
+
+	tk := NewTokenizer([]byte("<ABC>"))
+	tk.StrictHexLength = true
+	tk.SetPosition(0)
+	if _, err = tk.readAll(); err == nil {
+		t.Error("expected error for odd hex string")
+	}
+
+	tk.Reset([]byte("<AB C0> <>"))
+	tks, err = tk.readAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []byte{0xAB, 0xC0}; !bytes.Equal(tks[0].Value, exp) {
+		t.Errorf("expected %v, got %v", exp, tks[0].Value)
+	}
+}
+
+func TestStrictNameLength(t *testing.T) {
+	long := "/" + strings.Repeat("a", 200)
+	if _, err := Tokenize([]byte(long)); err != nil {
+		t.Fatal(err)
+	}
+
+	tk := NewTokenizer([]byte("/Type " + long))
+	tk.Strict = true
+	tk.SetPosition(0)
+	_, err := tk.readAll()
+	var tkErr *TokenizerError
+	if !errors.As(err, &tkErr) {
+		t.Fatalf("expected TokenizerError, got %v", err)
+	}
+	if tkErr.Pos != 6 {
+		t.Errorf("expected error at 6, got %d", tkErr.Pos)
+	}
+
+	// escape sequences count for one byte
+	tk.Reset([]byte("/" + strings.Repeat("#41", 127)))
+	if _, err = tk.readAll(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCharStringError(t *testing.T) {
+	_, err := Tokenize([]byte("/Private (a) RD xxx"))
+	var tkErr *TokenizerError
+	if !errors.As(err, &tkErr) {
+		t.Fatalf("expected TokenizerError, got %v", err)
+	}
+	if tkErr.Pos != 13 {
+		t.Errorf("expected error at 13, got %d", tkErr.Pos)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "13") || !strings.Contains(msg, "String") {
+		t.Errorf("expected position and previous kind in error, got %s", msg)
+	}
+}
+
+func TestNullInName(t *testing.T) {
+	input := []byte("/foo\x00bar")
+	// lenient mode: NUL is a white space
+	tks, err := Tokenize(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []Token{{Kind: Name, Value: []byte("foo")}, {Kind: Other, Value: []byte("bar")}}
+	if !reflect.DeepEqual(tks, exp) {
+		t.Errorf("expected %v, got %v", exp, tks)
+	}
+
+	tk := NewTokenizer(input)
+	tk.Strict = true
+	tk.SetPosition(0)
+	_, err = tk.readAll()
+	var tkErr *TokenizerError
+	if !errors.As(err, &tkErr) {
+		t.Fatalf("expected TokenizerError, got %v", err)
+	}
+	if tkErr.Pos != 4 || !strings.Contains(tkErr.Msg, "null byte") {
+		t.Errorf("unexpected error %s", err)
+	}
+}
+
+func TestTruncatedInput(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		pos   int
+		msg   string
+	}{
+		{"<", 0, "unexpected EOF in hex string"},
+		{"/A <41 4", 3, "unexpected EOF in hex string"},
+		{"<414 ", 0, "unexpected EOF in hex string"},
+		{">", 0, "unexpected EOF after '>'"},
+		{"<< /A 1 >", 8, "unexpected EOF after '>'"},
+		{"> >", 0, "'>' not expected"},
+		{"/abc#", 4, "truncated name escape"},
+		{"/abc#A", 4, "truncated name escape"},
+		{"/abc#A ", 4, "corrupted name object"},
+		{"(line\r", 0, "error reading string: unexpected EOF in string started"},
+	} {
+		_, err := Tokenize([]byte(test.input))
+		var tkErr *TokenizerError
+		if !errors.As(err, &tkErr) {
+			t.Fatalf("expected TokenizerError, got %v", err)
+		}
+		if tkErr.Pos != test.pos || tkErr.Msg != test.msg {
+			t.Errorf("expected error %q at %d, got %s", test.msg, test.pos, err)
+		}
+	}
+
+	tk := NewTokenizer([]byte("1 >"))
+	tk.NextToken()
+	if _, err := tk.NextToken(); err == nil {
+		t.Fatal("expected error")
+	}
+	if tk.pos != 3 {
+		t.Errorf("expected position 3, got %d", tk.pos)
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	tks, err := Tokenize([]byte("true false null R (true) /null Rx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, exp := range []struct {
+		value, isBool, isNull, isRef bool
+	}{
+		{true, true, false, false},
+		{false, true, false, false},
+		{false, false, true, false},
+		{false, false, false, true},
+		{},
+		{},
+		{},
+	} {
+		tk := tks[i]
+		value, isBool := tk.IsBool()
+		if value != exp.value || isBool != exp.isBool {
+			t.Errorf("%v: expected (%v, %v), got (%v, %v)", tk, exp.value, exp.isBool, value, isBool)
+		}
+		if tk.IsNull() != exp.isNull {
+			t.Errorf("%v: expected %v, got %v", tk, exp.isNull, tk.IsNull())
+		}
+		if tk.IsRef() != exp.isRef {
+			t.Errorf("%v: expected %v, got %v", tk, exp.isRef, tk.IsRef())
+		}
+	}
+}
+
+func TestCategory(t *testing.T) {
+	for kind, exp := range map[Kind]Category{
+		0:          0,
+		EOF:        End,
+		Float:      Scalar,
+		Integer:    Scalar,
+		String:     Scalar,
+		StringHex:  Scalar,
+		Name:       Scalar,
+		StartArray: Open,
+		EndArray:   Close,
+		StartDic:   Open,
+		EndDic:     Close,
+		Other:      Keyword,
+		StartProc:  Open,
+		EndProc:    Close,
+		CharString: Binary,
+		Comment:    Ignorable,
+		100:        0,
+	} {
+		if got := (Token{Kind: kind}).Category(); got != exp {
+			t.Errorf("%s: expected %s, got %s", kind, exp, got)
+		}
+	}
+}
+
+func TestUnterminatedString(t *testing.T) {
+	input := "BT /F1 12 Tf (ok) Tj (unterminated"
+	for _, tk := range []*Tokenizer{NewTokenizer([]byte(input)), NewTokenizerFromReader(strings.NewReader(input))} {
+		_, err := tk.readAll()
+		var tkErr *TokenizerError
+		if !errors.As(err, &tkErr) {
+			t.Fatalf("expected TokenizerError, got %v", err)
+		}
+		if tkErr.Pos != 21 {
+			t.Errorf("expected error at 21, got %d", tkErr.Pos)
+		}
+		if !strings.Contains(err.Error(), "offset 21") {
+			t.Errorf("expected start offset in error, got %s", err)
+		}
+	}
+}
+
+func TestSubTokenizer(t *testing.T) {
+	input := "1 0 obj << /A 2 >> endobj 2 0 obj (abc) endobj"
+	for _, tk := range []*Tokenizer{NewTokenizer([]byte(input)), NewTokenizerFromReader(strings.NewReader(input))} {
+		sub := tk.SubTokenizer(26, 43)
+		var positions []int
+		var tks []Token
+		for {
+			token, err := sub.NextToken()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.Kind == EOF {
+				break
+			}
+			tks = append(tks, token)
+			positions = append(positions, sub.CurrentPosition())
+		}
+		// the region ends inside the second 'endobj'
+		exp := []Token{
+			{Kind: Integer, Value: []byte("2")},
+			{Kind: Integer, Value: []byte("0")},
+			{Kind: Other, Value: []byte("obj")},
+			{Kind: String, Value: []byte("abc")},
+			{Kind: Other, Value: []byte("end")},
+		}
+		if !reflect.DeepEqual(tks, exp) {
+			t.Errorf("expected %v, got %v", exp, tks)
+		}
+		if expPos := []int{27, 29, 33, 39, 43}; !reflect.DeepEqual(positions, expPos) {
+			t.Errorf("expected %v, got %v", expPos, positions)
+		}
+
+		// the parent is not modified
+		if next, _ := tk.NextToken(); string(next.Value) != "1" {
+			t.Errorf("expected first token, got %v", next)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	input := "/A 1 0 R (abc) <01> [2] endobj"
+	for _, tk := range []*Tokenizer{
+		NewTokenizer([]byte(input)),
+		NewTokenizerFromReaderAt(strings.NewReader(input), int64(len(input)), WithReaderBufferSize(4)),
+		NewTokenizer([]byte(input), WithReuseValues()),
+	} {
+		tk.NextToken()
+		clone := tk.Clone()
+		// advance the clone up to the end
+		var last Token
+		for {
+			token, err := clone.NextToken()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.Kind == EOF {
+				break
+			}
+			last = token
+		}
+		if !last.IsOther("endobj") {
+			t.Errorf("expected endobj, got %v", last)
+		}
+		if pos := clone.CurrentPosition(); pos != len(input) {
+			t.Errorf("expected %d, got %d", len(input), pos)
+		}
+
+		// the original is not modified
+		if pos := tk.CurrentPosition(); pos != 2 {
+			t.Errorf("expected 2, got %d", pos)
+		}
+		for _, exp := range []string{"1", "0", "R", "abc"} {
+			next, err := tk.NextToken()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(next.Value) != exp {
+				t.Errorf("expected %s, got %v", exp, next)
+			}
+		}
+	}
+}
+
+func TestPeekN(t *testing.T) {
+	tk := NewTokenizer([]byte("1 0 R /Next 2 0 R"))
+	for i, exp := range []string{"1", "0", "R", "Next", "2"} {
+		token, err := tk.PeekN(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(token.Value) != exp {
+			t.Errorf("expected %s, got %v", exp, token)
+		}
+	}
+	// nothing is consumed
+	if token, _ := tk.NextToken(); string(token.Value) != "1" {
+		t.Errorf("expected 1, got %v", token)
+	}
+	if token, _ := tk.PeekN(3); string(token.Value) != "2" {
+		t.Errorf("expected 2, got %v", token)
+	}
+	if _, err := tk.PeekN(-1); err == nil {
+		t.Error("expected error for negative index")
+	}
+
+	// binary data is not tokenized
+	tk = NewTokenizer([]byte("<< /Length 6 >> stream\n(a) /b\nendstream"))
+	exp := []Token{
+		{Kind: Name, Value: []byte("Length")},
+		{Kind: Integer, Value: []byte("6")},
+		{Kind: EndDic},
+		{Kind: Other, Value: []byte("stream")},
+		{Kind: EOF},
+		{Kind: EOF},
+	}
+	tk.NextToken()
+	for i, exp := range exp {
+		token, err := tk.PeekN(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !token.Equal(exp) {
+			t.Errorf("expected %v, got %v", exp, token)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	input := []byte("<< /Type /Page /Kids [1 0 R] (s) >> {1 2 add}")
+	exp, err := Tokenize(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := NewTokenizer(input)
+	var got []Token
+	for tk.Scan() {
+		got = append(got, tk.Token())
+	}
+	if err = tk.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+	if tk.Scan() {
+		t.Error("expected false after EOF")
+	}
+
+	tk = NewTokenizer([]byte("1 2 (abc"))
+	nbTokens := 0
+	for tk.Scan() {
+		nbTokens++
+	}
+	if nbTokens != 2 || tk.Err() == nil {
+		t.Errorf("expected 2 tokens and an error, got %d and %v", nbTokens, tk.Err())
+	}
+	if tk.Scan() {
+		t.Error("expected false after an error")
+	}
+}
+
+func TestNextTokenSkipping(t *testing.T) {
+	tk := NewTokenizer([]byte("[[1] [2 [3]]] /a"))
+	var got []Token
+	for {
+		token, err := tk.NextTokenSkipping(StartArray, EndArray)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.Kind == EOF {
+			break
+		}
+		got = append(got, token)
+	}
+	exp := []Token{
+		{Kind: Integer, Value: []byte("1")},
+		{Kind: Integer, Value: []byte("2")},
+		{Kind: Integer, Value: []byte("3")},
+		{Kind: Name, Value: []byte("a")},
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+
+	tk = NewTokenizer([]byte("[ ]"))
+	if token, err := tk.NextTokenSkipping(StartArray, EndArray, EOF); err != nil || token.Kind != EOF {
+		t.Errorf("expected EOF, got %v (%v)", token, err)
+	}
+}
+
+func TestEmitComments(t *testing.T) {
+	input := []byte("%hello\n123 %%EOF")
+
+	tks, err := Tokenize(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []Token{{Kind: Integer, Value: []byte("123")}}; !reflect.DeepEqual(tks, exp) {
+		t.Errorf("expected %v, got %v", exp, tks)
+	}
+
+	tk := NewTokenizer(input)
+	tk.EmitComments = true
+	tk.SetPosition(0)
+	tks, err = tk.readAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []Token{
+		{Kind: Comment, Value: []byte("hello")},
+		{Kind: Integer, Value: []byte("123")},
+		{Kind: Comment, Value: []byte("%EOF")},
+	}
+	if !reflect.DeepEqual(tks, exp) {
+		t.Errorf("expected %v, got %v", exp, tks)
+	}
+}
+
+func TestManyComments(t *testing.T) {
+	input := strings.Repeat("%\n", 100_000) + "  %last\r\n42 " + strings.Repeat("%c\r", 100_000)
+	for _, tk := range []*Tokenizer{NewTokenizer([]byte(input)), NewTokenizerFromReader(strings.NewReader(input))} {
+		tks, err := tk.readAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := []Token{{Kind: Integer, Value: []byte("42")}}; !reflect.DeepEqual(tks, exp) {
+			t.Errorf("expected %v, got %v", exp, tks)
+		}
+	}
+}
+
+func TestNextTokenRequired(t *testing.T) {
+	tk := NewTokenizer([]byte("<< /Type"))
+	for _, exp := range []Kind{StartDic, Name} {
+		got, err := tk.NextTokenRequired()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Kind != exp {
+			t.Errorf("expected %s, got %s", exp, got.Kind)
+		}
+	}
+	_, err := tk.NextTokenRequired()
+	if err == nil {
+		t.Fatal("expected error at end of buffer")
+	}
+	if te, ok := err.(*TokenizerError); !ok || te.Pos != 8 {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestUnreadToken(t *testing.T) {
+	tk := NewTokenizer([]byte("[1 2] /A"))
+	if err := tk.UnreadToken(); err == nil {
+		t.Fatal("expected error before reading")
+	}
+
+	t1, _ := tk.NextToken() // [
+	t2, _ := tk.NextToken() // 1
+	if tk.AtTopLevel() {
+		t.Fatal("expected nested position")
+	}
+	if err := tk.UnreadToken(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tk.UnreadToken(); err == nil {
+		t.Fatal("expected error for second unread")
+	}
+	if got, _ := tk.LastToken(); !reflect.DeepEqual(got, t1) {
+		t.Errorf("expected %v, got %v", t1, got)
+	}
+	if got, _ := tk.PeekToken(); !reflect.DeepEqual(got, t2) {
+		t.Errorf("expected %v, got %v", t2, got)
+	}
+	if got, _ := tk.PeekPeekToken(); string(got.Value) != "2" {
+		t.Errorf("expected 2, got %v", got)
+	}
+	if tk.CurrentPosition() != 1 {
+		t.Errorf("expected 1, got %d", tk.CurrentPosition())
+	}
+
+	var kinds []Kind
+	for {
+		got, err := tk.NextToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Kind == EOF {
+			break
+		}
+		kinds = append(kinds, got.Kind)
+		if got.Kind == EndArray {
+			if !tk.AtTopLevel() {
+				t.Fatal("expected top level")
+			}
+			if err = tk.UnreadToken(); err != nil {
+				t.Fatal(err)
+			}
+			if tk.AtTopLevel() {
+				t.Fatal("expected nested position")
+			}
+			got, _ = tk.NextToken()
+			if got.Kind != EndArray {
+				t.Errorf("expected EndArray, got %s", got.Kind)
+			}
+		}
+	}
+	if exp := []Kind{Integer, Integer, EndArray, Name}; !reflect.DeepEqual(kinds, exp) {
+		t.Errorf("expected %v, got %v", exp, kinds)
+	}
+}
+
+func TestTokenizeAppend(t *testing.T) {
+	var scratch []Token
+	for _, input := range []string{"<< /Type /Page /Count 4 >>", "1 0 R", ""} {
+		var err error
+		scratch, err = TokenizeAppend(scratch, []byte(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp, _ := Tokenize([]byte(input))
+		if len(exp) != len(scratch) || (len(exp) != 0 && !reflect.DeepEqual(exp, scratch)) {
+			t.Errorf("expected %v, got %v", exp, scratch)
+		}
+	}
+}
+
+var benchObjects = [][]byte{
+	[]byte("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R >>"),
+	[]byte("[1 0 R 2 0 R 3 0 R 4 0 R 5 0 R]"),
+	[]byte("<< /Length 42 /Filter /FlateDecode >>"),
+}
+
+func BenchmarkTokenize(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, obj := range benchObjects {
+			if _, err := Tokenize(obj); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkTokenizeAppend(b *testing.B) {
+	b.ReportAllocs()
+	var scratch []Token
+	for i := 0; i < b.N; i++ {
+		for _, obj := range benchObjects {
+			var err error
+			scratch, err = TokenizeAppend(scratch, obj)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkNames(b *testing.B) {
+	for _, test := range []struct {
+		name  string
+		input []byte
+	}{
+		{"plain", []byte(strings.Repeat("/Type /Page /Font /F1 /BaseFont /Helvetica-Bold /Subtype /Type1\n", 500))},
+		{"escaped", []byte(strings.Repeat("/Type /Page /Font /F#31 /BaseFont /Helvetica#2DBold /Subtype /Type#31\n", 500))},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			b.ReportAllocs()
+			tk := NewTokenizer(nil)
+			for i := 0; i < b.N; i++ {
+				tk.Reset(test.input)
+				for {
+					t, err := tk.NextToken()
+					if err != nil {
+						b.Fatal(err)
+					}
+					if t.Kind == EOF {
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestLineContinuation(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		exp   string
+	}{
+		{"(line1\\\nline2)", "line1line2"},
+		{"(line1\\\rline2)", "line1line2"},
+		{"(line1\\\r\nline2)", "line1line2"},
+		{"(line1\\\n)", "line1"},
+		{"(line1\\\r)", "line1"},
+		{"(line1\\\r\n)", "line1"},
+		{"(a\\\n\\\r\\\r\nb)", "ab"},
+		{"(a\\\n\nb)", "a\nb"},   // only one EOL is removed
+		{"(a\\\r\rb)", "a\nb"},   // idem, with EOL normalization
+		{"(a\\\\\nb)", "a\\\nb"}, // escaped backslash
+		{"((a\\\n)\\\n)", "(a)"}, // nested parenthesis
+		{"(\\\n\\\n\\\n)", ""},   // only continuations
+		{"(a\\\n\\)b)", "a)b"},   // escaped parenthesis after continuation
+		{"(a\\\r\n\\\r\n)", "a"}, // repeated CRLF before the closing paren
+	} {
+		tks, err := Tokenize([]byte(test.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tks) != 1 || tks[0].Kind != String {
+			t.Fatalf("expected one string, got %v", tks)
+		}
+		if string(tks[0].Value) != test.exp {
+			t.Errorf("%q: expected %q, got %q", test.input, test.exp, tks[0].Value)
+		}
+	}
+
+	// the CRLF straddles the internal buffer end in reader mode
+	input := "(" + strings.Repeat("a", 1021) + "\\\r\nb)"
+	tks, err := NewTokenizerFromReader(strings.NewReader(input)).readAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := strings.Repeat("a", 1021) + "b"; len(tks) != 1 || string(tks[0].Value) != exp {
+		t.Errorf("unexpected tokens %v", tks)
+	}
+}
+
+func TestOctalEscapes(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		exp   string
+	}{
+		{`(\5)`, "\x05"},
+		{`(\05)`, "\x05"},
+		{`(\005)`, "\x05"},
+		{`(\0053)`, "\x053"},
+		{`(\5\05\005)`, "\x05\x05\x05"},
+	} {
+		tks, err := Tokenize([]byte(test.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tks) != 1 || string(tks[0].Value) != test.exp {
+			t.Errorf("%s: expected %q, got %v", test.input, test.exp, tks)
+		}
+	}
+
+	// truncated strings
+	for _, input := range []string{`(\05`, `(\5`, `(\005`, `(a\` + "\r"} {
+		tk := Tokenizer{data: []byte(input)}
+		if _, err := tk.nextToken(Token{}); err == nil {
+			t.Fatalf("%s: expected error", input)
+		}
+		if tk.pos != len(input) {
+			t.Errorf("%s: expected position %d, got %d", input, len(input), tk.pos)
+		}
+	}
+}
+
+func TestStringEOL(t *testing.T) {
+	for _, test := range []struct {
+		chunks chunkReader
+		exp    string
+	}{
+		{chunkReader{"(line\r)"}, "line\n"},
+		{chunkReader{"(line\r\n)"}, "line\n"},
+		{chunkReader{"(a\r\rb\n\r)"}, "a\n\nb\n\n"},
+		// the EOL straddles the chunks
+		{chunkReader{"(line\r", ")"}, "line\n"},
+		{chunkReader{"(line\r", "\n)"}, "line\n"},
+	} {
+		tks, err := NewTokenizerFromReader(&test.chunks).readAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tks) != 1 || string(tks[0].Value) != test.exp {
+			t.Errorf("expected %q, got %v", test.exp, tks)
+		}
+	}
+}
+
+func TestRemainingConsumed(t *testing.T) {
+	input := "1 0 obj << /Length 3 >> (abc) endobj  "
+	for _, tk := range []*Tokenizer{
+		NewTokenizer([]byte(input)),
+		NewTokenizerFromReaderAt(strings.NewReader(input), int64(len(input)), WithReaderBufferSize(4)),
+	} {
+		if tk.Remaining() != len(input) || tk.Consumed() != 0 {
+			t.Errorf("expected %d and 0, got %d and %d", len(input), tk.Remaining(), tk.Consumed())
+		}
+		for tk.Scan() {
+			if sum := tk.Remaining() + tk.Consumed(); sum != len(input) {
+				t.Errorf("expected %d, got %d", len(input), sum)
+			}
+			if tk.Consumed() != tk.CurrentPosition() {
+				t.Errorf("expected %d, got %d", tk.CurrentPosition(), tk.Consumed())
+			}
+		}
+		// EOF has been read
+		if tk.Remaining() != 0 || tk.Consumed() != len(input) {
+			t.Errorf("expected 0 and %d, got %d and %d", len(input), tk.Remaining(), tk.Consumed())
+		}
+	}
+}
+
+func TestRemainingReader(t *testing.T) {
+	input := "<< /Length 3 >>\nstream\nabc" + strings.Repeat("d", 2000)
+	for _, tk := range []*Tokenizer{
+		NewTokenizer([]byte(input)),
+		NewTokenizerFromReader(strings.NewReader(input)),
+	} {
+		for i := 0; i < 3; i++ { // << /Length 3
+			if _, err := tk.NextToken(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		rem, err := ioutil.ReadAll(tk.RemainingReader())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := input[len("<< /Length 3"):]; string(rem) != exp {
+			t.Errorf("expected %d bytes, got %d", len(exp), len(rem))
+		}
+	}
+}
+
+func TestReaderAt(t *testing.T) {
+	input := []byte("%PDF-1.4\n1 0 obj << /Type /Catalog >> endobj\n" +
+		strings.Repeat("% padding\n", 300) +
+		"2 0 obj (second) endobj\n" +
+		strings.Repeat("% padding\n", 300) +
+		"3 0 obj [1 2 3] endobj\n")
+	offsets := map[int]int{}
+	for num := 1; num <= 3; num++ {
+		offsets[num] = bytes.Index(input, []byte(strconv.Itoa(num)+" 0 obj"))
+	}
+
+	tk := NewTokenizerFromReaderAt(bytes.NewReader(input), int64(len(input)))
+	for _, num := range []int{3, 1, 2, 3, 2, 1} {
+		tk.SetPosition(offsets[num])
+		exp := NewTokenizer(input)
+		exp.SetPosition(offsets[num])
+		for i := 0; i < 6; i++ {
+			expTk, _ := exp.NextToken()
+			got, err := tk.NextToken()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, expTk) {
+				t.Fatalf("object %d: expected %v, got %v", num, expTk, got)
+			}
+			if tk.CurrentPosition() != exp.CurrentPosition() {
+				t.Fatalf("object %d: expected position %d, got %d", num, exp.CurrentPosition(), tk.CurrentPosition())
+			}
+		}
+	}
+	if len(tk.data) >= len(input)/2 { // the input is not entirely read
+		t.Errorf("unexpected buffer size %d", len(tk.data))
+	}
+
+	// error positions are offsets in the input
+	input = append(input, "(unterminated"...)
+	tk = NewTokenizerFromReaderAt(bytes.NewReader(input), int64(len(input)))
+	tk.SetPosition(len(input) - 13)
+	_, err := tk.NextToken()
+	if te, ok := err.(*TokenizerError); !ok || te.Pos != len(input)-13 {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+// repeatReader yields `n` times the given pattern
+type repeatReader struct {
+	pattern []byte
+	n, pos  int
+}
+
+func (rr *repeatReader) Read(p []byte) (int, error) {
+	written := 0
+	for written < len(p) && rr.n > 0 {
+		c := copy(p[written:], rr.pattern[rr.pos:])
+		written += c
+		rr.pos += c
+		if rr.pos == len(rr.pattern) {
+			rr.pos = 0
+			rr.n--
+		}
+	}
+	if written == 0 {
+		return 0, io.EOF
+	}
+	return written, nil
+}
+
+func TestCompactBuffer(t *testing.T) {
+	pattern := []byte("<< /Type /Page /MediaBox [0 0 612 792] /Contents (some text) >>\n")
+	const n = 200_000 // about 13MB
+	tk := NewTokenizerFromReader(&repeatReader{pattern: pattern, n: n})
+	nbDicts, maxBuffer := 0, 0
+	for {
+		tok, err := tk.NextToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Kind == EOF {
+			break
+		}
+		if tok.Kind == EndDic {
+			nbDicts++
+			if exp := nbDicts*len(pattern) - 1; tk.CurrentPosition() != exp {
+				t.Fatalf("expected position %d, got %d", exp, tk.CurrentPosition())
+			}
+			tk.CompactBuffer()
+		}
+		if c := cap(tk.data); c > maxBuffer {
+			maxBuffer = c
+		}
+	}
+	if nbDicts != n {
+		t.Errorf("expected %d dictionaries, got %d", n, nbDicts)
+	}
+	if maxBuffer > 8*1024 {
+		t.Errorf("buffer is not bounded: %d", maxBuffer)
+	}
+
+	// in memory mode, the input is not modified
+	input := []byte("1 2 3 4")
+	tk = NewTokenizer(input)
+	tk.NextToken()
+	tk.NextToken()
+	tk.CompactBuffer()
+	if tk.CurrentPosition() != 3 || string(tk.Bytes()) != " 3 4" || string(input) != "1 2 3 4" {
+		t.Errorf("unexpected state after compaction %d %q", tk.CurrentPosition(), tk.Bytes())
+	}
+	tk.SetPosition(5)
+	if tok, _ := tk.NextToken(); string(tok.Value) != "4" {
+		t.Errorf("expected 4, got %v", tok)
+	}
+	// the cached tokens are preserved with ZeroCopy
+	tk = NewTokenizerFromReader(strings.NewReader("1 2 3 4 5 6 7"), WithZeroCopy())
+	tk.NextToken()
+	tk.NextToken()
+	last, _ := tk.NextToken()
+	peeked, _ := tk.PeekToken()
+	tk.CompactBuffer()
+	if string(last.Value) != "3" || string(peeked.Value) != "4" {
+		t.Errorf("expected 3 and 4, got %v and %v", last, peeked)
+	}
+	for _, exp := range []string{"4", "5", "6", "7"} {
+		if tok, _ := tk.NextToken(); string(tok.Value) != exp {
+			t.Errorf("expected %s, got %v", exp, tok)
+		}
+	}
+}
+
+func TestNextTokenWithTerminator(t *testing.T) {
+	input := "/Name(a)/N2 12[4.5]true"
+	exp := []struct {
+		kind       Kind
+		terminator byte
+		ok         bool
+	}{
+		{Name, '(', true},
+		{String, '/', true},
+		{Name, ' ', true},
+		{Integer, '[', true},
+		{StartArray, '4', true},
+		{Float, ']', true},
+		{EndArray, 't', true},
+		{Other, 0, false},
+		{EOF, 0, false},
+	}
+	for _, tk := range []*Tokenizer{
+		NewTokenizer([]byte(input)),
+		NewTokenizerFromReader(strings.NewReader(input)),
+	} {
+		for _, e := range exp {
+			got, terminator, ok, err := tk.NextTokenWithTerminator()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Kind != e.kind || terminator != e.terminator || ok != e.ok {
+				t.Errorf("expected (%s, %q, %v), got (%s, %q, %v)", e.kind, e.terminator, e.ok, got.Kind, terminator, ok)
+			}
+		}
+	}
+}
+
+func TestSkipBytesReader(t *testing.T) {
+	data := bytes.Repeat([]byte{0, 1, 2, 3, 4, 0xff, '(', ')'}, 625) // 5000 bytes
+	input := append([]byte("BI /W 100 /H 50 ID "), data...)
+	input = append(input, "\nEI 1 2"...)
+	// read in small chunks
+	tk := NewTokenizerFromReader(iotest.OneByteReader(bytes.NewReader(input)), WithReaderBufferSize(1024))
+	for {
+		tok, err := tk.NextToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.IsOther("ID") {
+			break
+		}
+	}
+	tk.SkipBytes(1) // white space after ID
+	got := tk.SkipBytes(5000)
+	if !bytes.Equal(got, data) {
+		t.Fatalf("expected 5000 skipped bytes, got %d", len(got))
+	}
+	tks, err := tk.readAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tks) != 3 || !tks[0].IsOther("EI") {
+		t.Errorf("unexpected tokens after inline data %v", tks)
+	}
+
+	// truncated at the end of the input
+	tk = NewTokenizerFromReader(bytes.NewReader([]byte("0123456789")))
+	if got = tk.SkipBytes(5000); string(got) != "0123456789" {
+		t.Errorf("expected whole input, got %q", got)
+	}
+
+	// past EOF, with absurd lengths
+	for _, n := range []int{maxInt, 1 << 40} {
+		for _, tk := range []*Tokenizer{
+			NewTokenizer([]byte("ID 0123456789")),
+			NewTokenizerFromReader(strings.NewReader("ID 0123456789")),
+		} {
+			tk.NextToken()
+			if got = tk.SkipBytes(n); string(got) != " 0123456789" {
+				t.Errorf("expected remaining input, got %q", got)
+			}
+			if tok, _ := tk.NextToken(); tok.Kind != EOF {
+				t.Errorf("expected EOF, got %v", tok)
+			}
+		}
+	}
+}
+
+func TestCharStringCommand(t *testing.T) {
+	for _, cmd := range []string{"RD", "-|"} {
+		tks, err := Tokenize([]byte("/.notdef 12 " + cmd + " ............ ND"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := NewCharString([]byte(cmd), []byte("............"))
+		if len(tks) != 4 || !reflect.DeepEqual(tks[2], exp) {
+			t.Errorf("expected %v, got %v", exp, tks)
+		}
+	}
+}
+
+func TestCharStringLength(t *testing.T) {
+	for _, input := range []string{
+		"/a -5 RD abcdef ND",
+		"/a 99999999999 RD abcdef ND",
+		"/a 9223372036854775807 -| abc",
+		"/a 10 RD abc",
+	} {
+		for _, tk := range []*Tokenizer{
+			NewTokenizer([]byte(input)),
+			NewTokenizerFromReader(strings.NewReader(input)),
+		} {
+			_, err := tk.readAll()
+			if _, ok := err.(*TokenizerError); !ok {
+				t.Errorf("%s: expected error, got %v", input, err)
+			}
+		}
+	}
+
+	_, err := NewTokenizer([]byte("/a 12 RD ............"), WithMaxTokenSize(10)).readAll()
+	if err == nil {
+		t.Error("expected error for charstring exceeding MaxTokenSize")
+	}
+
+	// the charstring ends exactly at EOF
+	input := "/a 6 RD abcdef"
+	for _, tk := range []*Tokenizer{
+		NewTokenizer([]byte(input)),
+		NewTokenizerFromReader(strings.NewReader(input), WithReaderBufferSize(len(input))),
+		NewTokenizerFromReader(iotest.OneByteReader(strings.NewReader(input))),
+	} {
+		tks, err := tk.readAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tks) != 3 || string(tks[2].Value) != "abcdef" {
+			t.Errorf("expected full charstring, got %v", tks)
+		}
+	}
+}
+
+func TestTokenString(t *testing.T) {
+	for _, test := range []struct {
+		tk  Token
+		exp string
+	}{
+		{Token{Kind: Name, Value: []byte("Type")}, `Name("Type")`},
+		{Token{Kind: Float, Value: []byte("1.5")}, `Float("1.5")`},
+		{Token{Kind: String, Value: []byte("line1\nline2\x00")}, `String("line1\nline2\x00")`},
+		{Token{Kind: CharString, Value: make([]byte, 12)}, "CharString(12 bytes)"},
+		{Token{Kind: StartDic}, "StartDic"},
+		{Token{Kind: EOF}, "EOF"},
+	} {
+		if got := test.tk.String(); got != test.exp {
+			t.Errorf("expected %s, got %s", test.exp, got)
+		}
+	}
+}
+
+func TestPeekCouldBeObjHeader(t *testing.T) {
+	input := "1 0 R 2 0 obj << /A 3 0 R >> endobj 4 5 6 7 0 objx 8 0 obj"
+	for _, tk := range []*Tokenizer{
+		NewTokenizer([]byte(input)),
+		NewTokenizerFromReader(strings.NewReader(input)),
+	} {
+		var headers []string
+		for {
+			if tk.PeekCouldBeObjHeader() {
+				next, _ := tk.PeekToken()
+				headers = append(headers, string(next.Value))
+			}
+			got, err := tk.NextToken()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Kind == EOF {
+				break
+			}
+		}
+		if exp := []string{"2", "8"}; !reflect.DeepEqual(headers, exp) {
+			t.Errorf("expected %v, got %v", exp, headers)
+		}
+	}
+}
+
+func TestTokenEqual(t *testing.T) {
+	a := Token{Kind: Name, Value: []byte("Type")}
+	b := Token{Kind: Name, Value: append([]byte(nil), "Type"...)}
+	if &a.Value[0] == &b.Value[0] || !a.Equal(b) {
+		t.Error("expected equal tokens")
+	}
+	if a.Equal(Token{Kind: String, Value: []byte("Type")}) || a.Equal(Token{Kind: Name, Value: []byte("Typ")}) {
+		t.Error("expected different tokens")
+	}
+	if !(Token{Kind: EndDic}).Equal(Token{Kind: EndDic, Value: []byte{}}) {
+		t.Error("expected equal tokens for nil and empty values")
+	}
+}
+
+func TestExpectOneOf(t *testing.T) {
+	tk := NewTokenizer([]byte("12 4.5 /Name"))
+	for _, exp := range []Kind{Integer, Float} {
+		got, err := tk.ExpectOneOf(Integer, Float)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Kind != exp {
+			t.Errorf("expected %s, got %s", exp, got.Kind)
+		}
+	}
+	_, err := tk.ExpectOneOf(Integer, Float)
+	if err == nil {
+		t.Fatal("expected error for Name")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "Integer, Float") || !strings.Contains(msg, "got Name") {
+		t.Errorf("unexpected error message %s", msg)
+	}
+	if _, err = tk.ExpectOneOf(Integer); err == nil {
+		t.Error("expected error for EOF")
+	}
+}
+
+func TestKindClassifiers(t *testing.T) {
+	for kind := Kind(0); kind <= Comment+1; kind++ {
+		isOpen := kind == StartArray || kind == StartDic || kind == StartProc
+		isClose := kind == EndArray || kind == EndDic || kind == EndProc
+		isValue := kind == Float || kind == Integer || kind == String || kind == StringHex || kind == Name
+		if kind.IsOpen() != isOpen || kind.IsClose() != isClose || kind.IsValue() != isValue {
+			t.Errorf("%s: expected (%v, %v, %v), got (%v, %v, %v)", kind,
+				isOpen, isClose, isValue, kind.IsOpen(), kind.IsClose(), kind.IsValue())
+		}
+	}
+}
+
+func BenchmarkTokenizeType1(b *testing.B) {
+	input, err := ioutil.ReadFile("test/charstrings.ps")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Tokenize(input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package tokenizer
+
+// constants of the Type1 encryption (see the Adobe Type 1 Font Format, section 7)
+const (
+	type1C1 = 52845
+	type1C2 = 22719
+
+	eexecKey = 55665
+)
+
+// DecryptCharString decrypts `data` with the given key and
+// discards the first `skip` bytes of the result.
+// For charstrings, the key is 4330 and `skip` is the lenIV
+// entry of the Private dictionary (4 by default).
+func DecryptCharString(data []byte, r uint16, skip int) []byte {
+	out := make([]byte, len(data))
+	for i, c := range data {
+		out[i] = c ^ byte(r>>8)
+		r = (uint16(c)+r)*type1C1 + type1C2
+	}
+	if skip > len(out) {
+		skip = len(out)
+	}
+	if skip < 0 {
+		skip = 0
+	}
+	return out[skip:]
+}
+
+// DecryptEexec decrypts the binary `data` following the 'eexec' keyword
+// of a Type1 font, discarding the 4 random leading bytes.
+func DecryptEexec(data []byte) []byte {
+	return DecryptCharString(data, eexecKey, 4)
+}
@@ -0,0 +1,27 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestDecryptType1(t *testing.T) {
+	// the four zero bytes usually used as lenIV prefix
+	// are encrypted as 10 BF 31 70 (charstrings) and D9 D6 6F 63 (eexec)
+	cs, _ := hex.DecodeString("10bf31709aa9e33dee")
+	if got := DecryptCharString(cs, 4330, 4); string(got) != "hello" {
+		t.Errorf("expected hello, got %q", got)
+	}
+	if got := DecryptCharString(cs, 4330, 0); !bytes.Equal(got[:4], []byte{0, 0, 0, 0}) {
+		t.Errorf("expected zero prefix, got %v", got[:4])
+	}
+	if got := DecryptCharString(cs, 4330, 20); len(got) != 0 {
+		t.Errorf("expected empty result, got %v", got)
+	}
+
+	eexec, _ := hex.DecodeString("d9d66f633b846a989b9974b0179fc6cc")
+	if got := DecryptEexec(eexec); string(got) != "dup /Private" {
+		t.Errorf("expected dup /Private, got %q", got)
+	}
+}
@@ -0,0 +1,184 @@
+package tokenizer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// Canonicalize tokenizes `data` and writes the tokens back in a canonical form,
+// so that semantically equal inputs produce the same output:
+//   - tokens are separated by a single space, and comments are removed
+//   - numbers are written in decimal, without '+' sign, leading zeros or useless decimals,
+//     keeping the decimal point of real numbers
+//   - names use #XX escapes only when required
+//   - literal strings use the minimal escapes (see `EncodeLiteralString`)
+//   - hex strings are written with lower case digits
+//
+// As `Tokenize`, it stops at binary streams and inline data.
+func Canonicalize(data []byte) ([]byte, error) {
+	tk := NewTokenizer(data)
+	var out []byte
+	for {
+		t, err := tk.NextToken()
+		if err != nil {
+			return nil, err
+		}
+		if t.Kind == EOF {
+			return out, nil
+		}
+		if len(out) != 0 {
+			out = append(out, ' ')
+		}
+		out, err = appendCanonical(out, t)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// appendCanonical is the same as appendToken,
+// but also normalizes numbers
+func appendCanonical(dst []byte, t Token) ([]byte, error) {
+	switch t.Kind {
+	case Integer:
+		if i, err := t.Int64(); err == nil {
+			return strconv.AppendInt(dst, i, 10), nil
+		}
+		// larger integers
+		bi, err := t.BigInt()
+		if err != nil {
+			return nil, err
+		}
+		return bi.Append(dst, 10), nil
+	case Float:
+		return appendCanonicalFloat(dst, t.Value), nil
+	}
+	return appendToken(dst, t)
+}
+
+// appendCanonicalFloat removes the sign and zeros which are not required,
+// working on the text to avoid rounding errors and to keep the
+// decimal point (like 0.5 for +.50 or 5.0 for 005.)
+func appendCanonicalFloat(dst []byte, raw []byte) []byte {
+	v, neg := raw, false
+	if len(v) != 0 && (v[0] == '+' || v[0] == '-') {
+		v, neg = v[1:], v[0] == '-'
+	}
+	dot := bytes.IndexByte(v, '.')
+	if dot == -1 || bytes.IndexAny(v, "eE") != -1 { // exponential format : only remove '+'
+		if neg {
+			dst = append(dst, '-')
+		}
+		return append(dst, v...)
+	}
+	intPart := bytes.TrimLeft(v[:dot], "0")
+	frac := bytes.TrimRight(v[dot+1:], "0")
+	for _, c := range v[:dot] {
+		if !isDigit(c) {
+			return append(dst, raw...)
+		}
+	}
+	for _, c := range v[dot+1:] {
+		if !isDigit(c) {
+			return append(dst, raw...)
+		}
+	}
+	if neg && (len(intPart) != 0 || len(frac) != 0) { // -0.0 is written 0.0
+		dst = append(dst, '-')
+	}
+	if len(intPart) == 0 {
+		intPart = []byte{'0'}
+	}
+	if len(frac) == 0 {
+		frac = []byte{'0'}
+	}
+	dst = append(dst, intPart...)
+	dst = append(dst, '.')
+	return append(dst, frac...)
+}
+
+// WriteTokens writes the given tokens to `w`, separated by spaces,
+// so that tokenizing the output yields the same tokens:
+// names and literal strings are escaped, hex strings are written
+// in hexadecimal and numbers and keywords are written verbatim.
+// EOF tokens are ignored.
+func WriteTokens(w io.Writer, toks []Token) error {
+	var buf []byte
+	written := false
+	for _, t := range toks {
+		if t.Kind == EOF {
+			continue
+		}
+		buf = buf[:0]
+		if written {
+			buf = append(buf, ' ')
+		}
+		written = true
+		var err error
+		buf, err = appendToken(buf, t)
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendToken appends the source form of `t` to `dst`
+func appendToken(dst []byte, t Token) ([]byte, error) {
+	switch t.Kind {
+	case Name:
+		name, err := t.DecodeName()
+		if err != nil {
+			return nil, err
+		}
+		dst = append(dst, '/')
+		for _, c := range name {
+			if c < '!' || c > '~' || c == '#' || isDelimiter(c) {
+				dst = append(dst, '#', hexDigits[c>>4], hexDigits[c&0xF])
+			} else {
+				dst = append(dst, c)
+			}
+		}
+		return dst, nil
+	case String:
+		return append(dst, EncodeLiteralString(t.Value)...), nil
+	case StringHex:
+		dst = append(dst, '<')
+		for _, c := range t.Value {
+			dst = append(dst, hexDigits[c>>4], hexDigits[c&0xF])
+		}
+		return append(dst, '>'), nil
+	case StartArray:
+		return append(dst, '['), nil
+	case EndArray:
+		return append(dst, ']'), nil
+	case StartDic:
+		return append(dst, "<<"...), nil
+	case EndDic:
+		return append(dst, ">>"...), nil
+	case StartProc:
+		return append(dst, '{'), nil
+	case EndProc:
+		return append(dst, '}'), nil
+	case CharString:
+		if len(t.Command()) == 0 { // the data would be read as regular tokens
+			return nil, errors.New("missing command for CharString token")
+		}
+		dst = append(dst, t.Command()...)
+		dst = append(dst, ' ')
+		return append(dst, t.Value...), nil
+	case Comment:
+		dst = append(dst, '%')
+		dst = append(dst, t.Value...)
+		return append(dst, '\n'), nil
+	default: // numbers and keywords
+		return append(dst, t.Value...), nil
+	}
+}
+
+const hexDigits = "0123456789abcdef"
@@ -0,0 +1,117 @@
+package tokenizer
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	a := "<</Type /Page % comment\n /Count 007 /Scale 1.50 /Off -.5 /N#41me (a\\nb\\101)\n/Id <AB CD>>>\n[1  2.0 +3]{ pop }"
+	b := "<< /Type/Page/Count 7/Scale 1.5 /Off -0.5 /NAme (a\nbA) /Id <abcd> >> [ 1 2. 3 ] {pop}"
+	ca, err := Canonicalize([]byte(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err := Canonicalize([]byte(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ca) != string(cb) {
+		t.Errorf("expected identical output, got %q and %q", ca, cb)
+	}
+	exp := "<< /Type /Page /Count 7 /Scale 1.5 /Off -0.5 /NAme (a\\nbA) /Id <abcd> >> [ 1 2.0 3 ] { pop }"
+	if string(ca) != exp {
+		t.Errorf("expected %q, got %q", exp, ca)
+	}
+
+	out, err := Canonicalize([]byte("/A#20b 16#FF 99999999999999999999 << /Length 2 >> stream\n\x00\x01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "/A#20b 255 99999999999999999999 << /Length 2 >> stream"; string(out) != exp {
+		t.Errorf("expected %q, got %q", exp, out)
+	}
+
+	// numbers keep their kind and precision
+	for _, test := range []struct {
+		input, exp string
+	}{
+		{"5.", "5.0"},
+		{"5.0", "5.0"},
+		{"+005.500", "5.5"},
+		{"-.5", "-0.5"},
+		{"-0.0", "0.0"},
+		{"+.0", "0.0"},
+		{"0.1000000000000000055511", "0.1000000000000000055511"},
+		{"123456789012345678901234.5", "123456789012345678901234.5"},
+		{"+1.5e3", "1.5e3"},
+		{"-007", "-7"},
+		{"+0", "0"},
+	} {
+		out, err := Canonicalize([]byte(test.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != test.exp {
+			t.Errorf("expected %q, got %q", test.exp, out)
+		}
+		tks, _ := Tokenize([]byte(test.input))
+		got, err := Tokenize(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].Kind != tks[0].Kind {
+			t.Errorf("%s: expected %s, got %v", test.input, tks[0].Kind, got)
+		}
+	}
+
+	if _, err = Canonicalize([]byte("(abc")); err == nil {
+		t.Error("expected error for invalid input")
+	}
+}
+
+func TestWriteTokens(t *testing.T) {
+	input := "%!PS\n<< /Type /Pa#20ge /Kids [1 0 R] /S (a\\(b\\)\r\nc\\001) /H <0aFF> /N -1.5e3 >>\n" +
+		"{ dup 16#FF gt } bind def /a 3 RD a\x00) ND /Sym#23#2F%comment\n[/]"
+	tks, err := NewTokenizer([]byte(input), WithComments()).readAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = WriteTokens(&buf, append(tks, Token{Kind: EOF})); err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewTokenizer(buf.Bytes(), WithComments()).readAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(tks) {
+		t.Fatalf("expected %v, got %v", tks, got)
+	}
+	for i, tk := range tks {
+		if tk.Kind == Name { // escapes may differ
+			exp, _ := tk.DecodeName()
+			name, _ := got[i].DecodeName()
+			tk.Value, got[i].Value = exp, name
+		}
+		if !tk.Equal(got[i]) || !bytes.Equal(tk.Command(), got[i].Command()) {
+			t.Errorf("expected %v, got %v", tk, got[i])
+		}
+	}
+
+	if err = WriteTokens(&buf, []Token{{Kind: Name, Value: []byte("a#zz")}}); err == nil {
+		t.Error("expected error for invalid name")
+	}
+	if err = WriteTokens(&buf, []Token{{Kind: Integer, Value: []byte("2")}, {Kind: CharString, Value: []byte("ab")}}); err == nil {
+		t.Error("expected error for CharString without command")
+	}
+
+	// separators are only written between tokens
+	buf.Reset()
+	if err = WriteTokens(&buf, []Token{{Kind: EOF}, {Kind: Name, Value: []byte("a")}, {Kind: EOF}, {Kind: StartArray}}); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "/a ["; buf.String() != exp {
+		t.Errorf("expected %q, got %q", exp, buf.String())
+	}
+}